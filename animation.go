@@ -0,0 +1,191 @@
+package koebiten
+
+// AnimationMode represents how an Animation plays its frames.
+type AnimationMode int
+
+const (
+	// AnimationLoop plays the frames from the first to the last, then starts
+	// again from the first.
+	AnimationLoop AnimationMode = iota
+
+	// AnimationPingPong plays the frames from the first to the last, then
+	// back to the first, and so on.
+	AnimationPingPong
+
+	// AnimationOnce plays the frames once and stops on the last one.
+	AnimationOnce
+)
+
+// AnimationFrame is a frame of an Animation.
+type AnimationFrame struct {
+	// Image is the image of the frame.
+	Image *Image
+
+	// Duration is the number of ticks the frame is shown.
+	// A value less than 1 is treated as 1.
+	Duration int
+}
+
+// Animation is a sequence of images, each shown for a number of ticks.
+//
+// Call Update once per tick, usually from Game.Update, and Draw to draw the
+// current frame.
+type Animation struct {
+	frames   []AnimationFrame
+	mode     AnimationMode
+	frame    int
+	tick     int
+	dir      int
+	paused   bool
+	finished bool
+	onFinish func()
+}
+
+// NewAnimation creates an Animation that plays frames with mode.
+func NewAnimation(frames []AnimationFrame, mode AnimationMode) *Animation {
+	return &Animation{
+		frames: frames,
+		mode:   mode,
+		dir:    1,
+	}
+}
+
+// NewAnimationFromSpriteSheet creates an Animation that plays all the frames
+// of s in order, each for duration ticks.
+//
+// Use NewSpriteSheet to slice a strip of frames:
+//
+//	s := koebiten.NewSpriteSheet(strip, 16, 16)
+//	a := koebiten.NewAnimationFromSpriteSheet(s, 8, koebiten.AnimationLoop)
+func NewAnimationFromSpriteSheet(s *SpriteSheet, duration int, mode AnimationMode) *Animation {
+	frames := make([]AnimationFrame, s.Len())
+	for i := range frames {
+		frames[i] = AnimationFrame{Image: s.Frame(i), Duration: duration}
+	}
+	return NewAnimation(frames, mode)
+}
+
+// Update advances the animation by one tick. It does nothing while the
+// animation is paused or finished.
+func (a *Animation) Update() {
+	if a.paused || a.finished || len(a.frames) == 0 {
+		return
+	}
+	a.tick++
+	if a.tick < max(a.frames[a.frame].Duration, 1) {
+		return
+	}
+	a.tick = 0
+
+	n := len(a.frames)
+	switch a.mode {
+	case AnimationLoop:
+		a.frame = (a.frame + 1) % n
+		if a.frame == 0 {
+			a.finish()
+		}
+	case AnimationPingPong:
+		if n > 1 {
+			if next := a.frame + a.dir; next < 0 || n <= next {
+				a.dir = -a.dir
+			}
+			a.frame += a.dir
+		}
+		if a.frame == 0 {
+			a.finish()
+		}
+	case AnimationOnce:
+		if a.frame == n-1 {
+			a.finished = true
+			a.finish()
+			return
+		}
+		a.frame++
+	}
+}
+
+func (a *Animation) finish() {
+	if a.onFinish != nil {
+		a.onFinish()
+	}
+}
+
+// Draw draws the current frame to dst with options, as Image.DrawImage does.
+func (a *Animation) Draw(dst Displayer, options DrawImageOptions) {
+	if img := a.Frame(); img != nil {
+		img.DrawImage(dst, options)
+	}
+}
+
+// Frame returns the image of the current frame, or nil if there are no
+// frames.
+func (a *Animation) Frame() *Image {
+	if len(a.frames) == 0 {
+		return nil
+	}
+	return a.frames[a.frame].Image
+}
+
+// FrameIndex returns the index of the current frame.
+func (a *Animation) FrameIndex() int {
+	return a.frame
+}
+
+// Len returns the number of frames.
+func (a *Animation) Len() int {
+	return len(a.frames)
+}
+
+// Mode returns the playback mode.
+func (a *Animation) Mode() AnimationMode {
+	return a.mode
+}
+
+// SetMode sets the playback mode.
+func (a *Animation) SetMode(mode AnimationMode) {
+	a.mode = mode
+	a.dir = 1
+}
+
+// SetOnFinish sets the function called when the animation finishes.
+// A looping or ping-pong animation finishes each time it comes back to the
+// first frame.
+func (a *Animation) SetOnFinish(f func()) {
+	a.onFinish = f
+}
+
+// Play resumes the animation.
+func (a *Animation) Play() {
+	a.paused = false
+}
+
+// Pause pauses the animation. The current frame keeps being drawn.
+func (a *Animation) Pause() {
+	a.paused = true
+}
+
+// IsPaused reports whether the animation is paused.
+func (a *Animation) IsPaused() bool {
+	return a.paused
+}
+
+// IsFinished reports whether an AnimationOnce animation has shown its last
+// frame for its whole duration.
+func (a *Animation) IsFinished() bool {
+	return a.finished
+}
+
+// Seek moves the animation to the start of the i-th frame. i is clamped to
+// the range of the frames.
+func (a *Animation) Seek(i int) {
+	a.frame = min(max(i, 0), max(len(a.frames)-1, 0))
+	a.tick = 0
+	a.finished = false
+}
+
+// Reset moves the animation back to the start of the first frame, in the
+// forward direction. It doesn't change whether the animation is paused.
+func (a *Animation) Reset() {
+	a.Seek(0)
+	a.dir = 1
+}
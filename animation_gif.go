@@ -0,0 +1,71 @@
+//go:build !tinygo
+
+package koebiten
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"io/fs"
+)
+
+// NewAnimationFromGIF creates an Animation from an animated GIF image in the
+// filesystem. The colors of the frames are converted as by NewImageFromImage
+// with options, and the delays are converted to ticks at the current TPS.
+// If options is nil, the default options are used.
+//
+// The animation loops, unless the GIF is meant to be played once.
+//
+// NewAnimationFromGIF is only built on the host, so that the GIF decoder is
+// not linked into the firmware. Use NewAnimationFromSpriteSheet on boards.
+func NewAnimationFromGIF(fsys fs.FS, path string, options *NewImageFromImageOptions) (*Animation, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	g, err := gif.DecodeAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// Frames of a GIF image may cover only a part of the image, so they are
+	// composed on a canvas with their disposal methods.
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	var previous *image.RGBA
+	frames := make([]AnimationFrame, len(g.Image))
+	for i, p := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		if disposal == gif.DisposalPrevious {
+			previous = image.NewRGBA(canvas.Rect)
+			copy(previous.Pix, canvas.Pix)
+		}
+		draw.Draw(canvas, p.Bounds(), p, p.Bounds().Min, draw.Over)
+
+		var delay int
+		if i < len(g.Delay) {
+			delay = g.Delay[i]
+		}
+		frames[i] = AnimationFrame{
+			Image:    NewImageFromImage(canvas, options),
+			Duration: max((delay*TPS()+50)/100, 1),
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, p.Bounds(), image.Transparent, image.Point{}, draw.Src)
+		case gif.DisposalPrevious:
+			copy(canvas.Pix, previous.Pix)
+		}
+	}
+
+	mode := AnimationLoop
+	if g.LoopCount < 0 {
+		mode = AnimationOnce
+	}
+	return NewAnimation(frames, mode), nil
+}
@@ -0,0 +1,123 @@
+package koebiten_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sago35/koebiten"
+)
+
+func TestAnimationModes(t *testing.T) {
+	frames := []koebiten.AnimationFrame{
+		{Image: koebiten.NewImage(1, 1), Duration: 2},
+		{Image: koebiten.NewImage(1, 1), Duration: 1},
+		{Image: koebiten.NewImage(1, 1), Duration: 1},
+	}
+	for _, tc := range []struct {
+		mode     koebiten.AnimationMode
+		want     []int
+		finishes int
+	}{
+		{koebiten.AnimationLoop, []int{0, 0, 1, 2, 0, 0, 1, 2}, 2},
+		{koebiten.AnimationPingPong, []int{0, 0, 1, 2, 1, 0, 0, 1}, 1},
+		{koebiten.AnimationOnce, []int{0, 0, 1, 2, 2, 2, 2, 2}, 1},
+	} {
+		a := koebiten.NewAnimation(frames, tc.mode)
+		finishes := 0
+		a.SetOnFinish(func() { finishes++ })
+		var got []int
+		for range tc.want {
+			got = append(got, a.FrameIndex())
+			a.Update()
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("mode %d: frames = %v, want %v", tc.mode, got, tc.want)
+				break
+			}
+		}
+		if finishes != tc.finishes {
+			t.Errorf("mode %d: finished %d times, want %d", tc.mode, finishes, tc.finishes)
+		}
+		if got, want := a.IsFinished(), tc.mode == koebiten.AnimationOnce; got != want {
+			t.Errorf("mode %d: IsFinished() = %v, want %v", tc.mode, got, want)
+		}
+	}
+}
+
+func TestAnimationPauseAndSeek(t *testing.T) {
+	s := koebiten.NewSpriteSheet(koebiten.NewImage(12, 4), 4, 4)
+	a := koebiten.NewAnimationFromSpriteSheet(s, 1, koebiten.AnimationOnce)
+	if got := a.Len(); got != 3 {
+		t.Fatalf("Len() = %d, want 3", got)
+	}
+
+	a.Pause()
+	a.Update()
+	if got := a.FrameIndex(); got != 0 {
+		t.Errorf("paused FrameIndex() = %d, want 0", got)
+	}
+	a.Play()
+	a.Seek(2)
+	a.Update()
+	if !a.IsFinished() {
+		t.Errorf("IsFinished() = false after the last frame")
+	}
+	a.Reset()
+	if a.IsFinished() || a.FrameIndex() != 0 {
+		t.Errorf("Reset() didn't go back to the first frame")
+	}
+}
+
+func TestAnimationFromGIF(t *testing.T) {
+	palette := color.Palette{color.Transparent, color.Black, color.White}
+	full := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+	full.SetColorIndex(0, 0, 2)
+	// The second frame only covers a part of the image and keeps the
+	// pixels of the first frame.
+	part := image.NewPaletted(image.Rect(2, 2, 4, 4), palette)
+	part.SetColorIndex(3, 3, 2)
+
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{
+		Image:     []*image.Paletted{full, part},
+		Delay:     []int{10, 20},
+		Disposal:  []byte{gif.DisposalNone, gif.DisposalNone},
+		LoopCount: -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"anim.gif": &fstest.MapFile{Data: buf.Bytes()}}
+
+	a, err := koebiten.NewAnimationFromGIF(fsys, "anim.gif", &koebiten.NewImageFromImageOptions{Invert: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Len(); got != 2 {
+		t.Fatalf("Len() = %d, want 2", got)
+	}
+	if got := a.Mode(); got != koebiten.AnimationOnce {
+		t.Errorf("Mode() = %d, want AnimationOnce", got)
+	}
+
+	// 10/100 seconds at the default 60 TPS.
+	for range 6 {
+		a.Update()
+	}
+	if got := a.FrameIndex(); got != 1 {
+		t.Fatalf("FrameIndex() = %d, want 1", got)
+	}
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	img := a.Frame()
+	if got := img.PixelAt(0, 0); got != white {
+		t.Errorf("pixel from the first frame = %v, want white", got)
+	}
+	if got := img.PixelAt(3, 3); got != white {
+		t.Errorf("pixel from the second frame = %v, want white", got)
+	}
+}
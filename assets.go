@@ -0,0 +1,321 @@
+package koebiten
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/fs"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrAssetBudgetExceeded is returned by Assets when an image doesn't fit
+	// in the memory budget, even after the unused images are unloaded.
+	ErrAssetBudgetExceeded = errors.New("koebiten: asset memory budget exceeded")
+
+	// ErrInvalidPNG is returned when a file is not a PNG image.
+	ErrInvalidPNG = errors.New("koebiten: invalid PNG image")
+)
+
+// LoadImage loads a monochrome Image from a PNG image in the filesystem, like
+// NewImageFromFS, but returns an error instead of panicking.
+func LoadImage(fsys fs.FS, path string) (*Image, error) {
+	return loadImageFromFS(fsys, path, FormatMonochrome)
+}
+
+// Assets is a cache of the images loaded from filesystems, so that games and
+// scenes can share decoded images.
+//
+// Images are keyed by the fs.FS and the path. Each Load adds a reference to
+// the image and each Release removes one. Images without references stay in
+// the cache until they are unloaded, either explicitly or to make room for
+// another image when the memory budget is exceeded.
+//
+// Filesystems are identified by their address if they are pointers or maps,
+// such as fstest.MapFS, or by their value if it is comparable, such as
+// embed.FS. The images of the other filesystems are not cached, so every Load
+// decodes them again.
+//
+// An Assets is safe for concurrent use by multiple goroutines.
+type Assets struct {
+	m       sync.Mutex
+	budget  int
+	used    int
+	clock   uint64
+	entries map[assetKey]*assetEntry
+}
+
+type assetKey struct {
+	fsys any
+	path string
+
+	// options are the options of NewImageFromFSWithOptions, if hasOptions is
+	// true. Otherwise the image is loaded by LoadImage. The threshold is
+	// kept by value, without the pointer of options.
+	options    NewImageFromImageOptions
+	threshold  uint8
+	hasOptions bool
+}
+
+type assetEntry struct {
+	img     *Image
+	size    int
+	refs    int
+	lastUse uint64
+}
+
+// NewAssets creates an Assets whose images take at most budget bytes of
+// pixels. If budget is 0, there is no limit.
+func NewAssets(budget int) *Assets {
+	return &Assets{
+		budget:  budget,
+		entries: map[assetKey]*assetEntry{},
+	}
+}
+
+// Load returns the image at path in fsys and adds a reference to it.
+// The image is loaded as by LoadImage if it is not in the cache yet.
+//
+// If the image doesn't fit in the budget, Load returns ErrAssetBudgetExceeded
+// without decoding it, so optional assets can be skipped safely.
+func (a *Assets) Load(fsys fs.FS, path string) (*Image, error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	e, err := a.load(fsys, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	e.refs++
+	return e.img, nil
+}
+
+// Preload loads the images at paths in fsys into the cache without adding
+// references, so that later calls of Load don't decode them.
+func (a *Assets) Preload(fsys fs.FS, paths ...string) error {
+	a.m.Lock()
+	defer a.m.Unlock()
+	for _, path := range paths {
+		if _, err := a.load(fsys, path, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Release removes a reference added by Load. An image without references
+// stays in the cache, but it can be unloaded to make room for other images.
+func (a *Assets) Release(fsys fs.FS, path string) {
+	key, ok := newAssetKey(fsys, path)
+	if !ok {
+		return
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if e, ok := a.entries[key]; ok && e.refs > 0 {
+		e.refs--
+	}
+}
+
+// Unload removes the image at path in fsys from the cache, even if it has
+// references. The Image values returned by Load stay valid, but they are not
+// shared with later loads anymore.
+func (a *Assets) Unload(fsys fs.FS, path string) {
+	key, ok := newAssetKey(fsys, path)
+	if !ok {
+		return
+	}
+	a.m.Lock()
+	defer a.m.Unlock()
+	if e, ok := a.entries[key]; ok {
+		a.used -= e.size
+		delete(a.entries, key)
+	}
+}
+
+// UnloadUnused removes the images without references from the cache.
+func (a *Assets) UnloadUnused() {
+	a.m.Lock()
+	defer a.m.Unlock()
+	for key, e := range a.entries {
+		if e.refs == 0 {
+			a.used -= e.size
+			delete(a.entries, key)
+		}
+	}
+}
+
+// Len returns the number of images in the cache.
+func (a *Assets) Len() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return len(a.entries)
+}
+
+// Used returns the number of bytes of pixels of the images in the cache.
+func (a *Assets) Used() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.used
+}
+
+// Budget returns the memory budget in bytes. 0 means there is no limit.
+func (a *Assets) Budget() int {
+	a.m.Lock()
+	defer a.m.Unlock()
+	return a.budget
+}
+
+// SetBudget sets the memory budget in bytes. 0 means there is no limit.
+// Images without references are unloaded until the cache fits in the new
+// budget.
+func (a *Assets) SetBudget(budget int) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	a.budget = budget
+	a.reserve(0)
+}
+
+// loadUnreferenced returns the image at path in fsys like Load, without
+// adding a reference.
+func (a *Assets) loadUnreferenced(fsys fs.FS, path string, options *NewImageFromImageOptions) (*Image, error) {
+	a.m.Lock()
+	defer a.m.Unlock()
+	e, err := a.load(fsys, path, options)
+	if err != nil {
+		return nil, err
+	}
+	return e.img, nil
+}
+
+// load returns the cache entry of the image at path in fsys. If options is
+// not nil, the image is loaded as by NewImageFromFSWithOptions instead of
+// LoadImage, and cached separately. a.m must be held.
+func (a *Assets) load(fsys fs.FS, path string, options *NewImageFromImageOptions) (*assetEntry, error) {
+	a.clock++
+	key, cacheable := newAssetKey(fsys, path)
+	format := FormatMonochrome
+	if options != nil {
+		key.options = *options
+		key.options.Threshold = nil
+		key.threshold = 0x80
+		if options.Threshold != nil {
+			key.threshold = *options.Threshold
+		}
+		key.hasOptions = true
+		format = options.Format
+	}
+	if e, ok := a.entries[key]; cacheable && ok {
+		e.lastUse = a.clock
+		return e, nil
+	}
+
+	w, h, err := pngSize(fsys, path)
+	if err != nil {
+		return nil, err
+	}
+	size := imageBytes(format, w, h)
+	if cacheable && !a.reserve(size) {
+		return nil, ErrAssetBudgetExceeded
+	}
+	var img *Image
+	if options != nil {
+		img, err = loadImageFromFSWithOptions(fsys, path, options)
+	} else {
+		img, err = LoadImage(fsys, path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !cacheable {
+		// The image can't be shared without knowing which filesystem it
+		// comes from.
+		return &assetEntry{img: img, size: size, lastUse: a.clock}, nil
+	}
+	e := &assetEntry{
+		img:     img,
+		size:    size,
+		lastUse: a.clock,
+	}
+	a.entries[key] = e
+	a.used += size
+	return e, nil
+}
+
+// reserve unloads the least recently used images without references until
+// size more bytes fit in the budget. It reports whether they fit.
+func (a *Assets) reserve(size int) bool {
+	if a.budget <= 0 {
+		return true
+	}
+	for a.used+size > a.budget {
+		var lru assetKey
+		var oldest *assetEntry
+		for key, e := range a.entries {
+			if e.refs == 0 && (oldest == nil || e.lastUse < oldest.lastUse) {
+				lru, oldest = key, e
+			}
+		}
+		if oldest == nil {
+			return false
+		}
+		a.used -= oldest.size
+		delete(a.entries, lru)
+	}
+	return true
+}
+
+func newAssetKey(fsys fs.FS, path string) (assetKey, bool) {
+	id, ok := fsKey(fsys)
+	return assetKey{fsys: id, path: path}, ok
+}
+
+// fsPointer identifies a filesystem by its address.
+type fsPointer struct {
+	typ reflect.Type
+	ptr uintptr
+}
+
+// fsKey returns a comparable value that identifies fsys, and reports whether
+// fsys can be identified.
+func fsKey(fsys fs.FS) (any, bool) {
+	if fsys == nil {
+		return nil, false
+	}
+	v := reflect.ValueOf(fsys)
+	switch v.Kind() {
+	case reflect.Map, reflect.Ptr, reflect.UnsafePointer, reflect.Chan:
+		return fsPointer{typ: v.Type(), ptr: v.Pointer()}, true
+	}
+	// Comparable reports false for the values that hold non-comparable
+	// values in interfaces, which would panic as map keys.
+	if v.Comparable() {
+		return fsys, true
+	}
+	return nil, false
+}
+
+// imageBytes returns the number of bytes of the pixels of an image.
+func imageBytes(format PixelFormat, width, height int) int {
+	return (width*format.BitsPerPixel() + 7) / 8 * height
+}
+
+// pngSize reads the size of a PNG image from its header without decoding it.
+func pngSize(fsys fs.FS, path string) (width, height int, err error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	// The signature, then the length and the type of the IHDR chunk, then
+	// the width and the height.
+	var header [24]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		return 0, 0, ErrInvalidPNG
+	}
+	if string(header[:8]) != "\x89PNG\r\n\x1a\n" || string(header[12:16]) != "IHDR" {
+		return 0, 0, ErrInvalidPNG
+	}
+	return int(binary.BigEndian.Uint32(header[16:20])), int(binary.BigEndian.Uint32(header[20:24])), nil
+}
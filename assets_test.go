@@ -0,0 +1,217 @@
+package koebiten_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sago35/koebiten"
+)
+
+// pngFile encodes a w x h PNG image with a black pixel at (0, 0) on white.
+func pngFile(t *testing.T, w, h int) *fstest.MapFile {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	img.SetRGBA(0, 0, color.RGBA{A: 0xFF})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return &fstest.MapFile{Data: buf.Bytes()}
+}
+
+func TestLoadImage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.png":   pngFile(t, 16, 8),
+		"bad.png": &fstest.MapFile{Data: []byte("not a png")},
+	}
+	img, err := koebiten.LoadImage(fsys, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w, h := img.Size(); w != 16 || h != 8 {
+		t.Errorf("Size() = %d, %d, want 16, 8", w, h)
+	}
+	if r, _, _, _ := img.At(0, 0).RGBA(); r == 0 {
+		t.Errorf("dark pixel is not set")
+	}
+	if _, err := koebiten.LoadImage(fsys, "missing.png"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v, want fs.ErrNotExist", err)
+	}
+	if _, err := koebiten.LoadImage(fsys, "bad.png"); err == nil {
+		t.Errorf("invalid file: no error")
+	}
+}
+
+func TestAssets(t *testing.T) {
+	// Each image takes 16 * 8 / 8 = 16 bytes.
+	fsys := fstest.MapFS{
+		"a.png": pngFile(t, 16, 8),
+		"b.png": pngFile(t, 16, 8),
+		"c.png": pngFile(t, 16, 8),
+	}
+	other := fstest.MapFS{
+		"a.png": pngFile(t, 8, 8),
+	}
+	a := koebiten.NewAssets(32)
+
+	img1, err := a.Load(fsys, "a.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img2, _ := a.Load(fsys, "a.png")
+	if img1 != img2 {
+		t.Errorf("Load of the same asset returned different images")
+	}
+	img3, _ := a.Load(other, "a.png")
+	if img1 == img3 {
+		t.Errorf("Load of an asset in another filesystem returned the same image")
+	}
+	if got := a.Used(); got != 24 {
+		t.Errorf("Used() = %d, want 24", got)
+	}
+
+	// a.png is referenced, so b.png doesn't fit until other's a.png is released.
+	if _, err := a.Load(fsys, "b.png"); !errors.Is(err, koebiten.ErrAssetBudgetExceeded) {
+		t.Fatalf("Load over budget: got %v, want ErrAssetBudgetExceeded", err)
+	}
+	a.Release(other, "a.png")
+	if err := a.Preload(fsys, "b.png"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Len(); got != 2 {
+		t.Errorf("Len() = %d, want 2", got)
+	}
+
+	// Preloaded images have no references and are evicted first.
+	a.Release(fsys, "a.png")
+	if _, err := a.Load(fsys, "c.png"); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Used(); got != 32 {
+		t.Errorf("Used() = %d, want 32", got)
+	}
+
+	a.Unload(fsys, "c.png")
+	a.UnloadUnused()
+	if got := a.Len(); got != 1 {
+		t.Errorf("Len() = %d, want 1", got)
+	}
+	if img, _ := a.Load(fsys, "a.png"); img != img1 {
+		t.Errorf("referenced image was unloaded")
+	}
+}
+
+// funcFS is a filesystem whose type is not comparable.
+type funcFS func(name string) (fs.File, error)
+
+func (f funcFS) Open(name string) (fs.File, error) {
+	return f(name)
+}
+
+// wrapFS is a comparable filesystem that holds a non-comparable one.
+type wrapFS struct {
+	fs.FS
+}
+
+func TestAssetsUnidentifiedFS(t *testing.T) {
+	small := fstest.MapFS{"a.png": pngFile(t, 2, 1)}
+	large := fstest.MapFS{"a.png": pngFile(t, 4, 1)}
+	a := koebiten.NewAssets(0)
+
+	for _, tt := range []struct {
+		name         string
+		small, large fs.FS
+	}{
+		{"func", funcFS(small.Open), funcFS(large.Open)},
+		{"struct of map", wrapFS{small}, wrapFS{large}},
+	} {
+		img1, err := a.Load(tt.small, "a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		img2, err := a.Load(tt.large, "a.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if w, _ := img1.Size(); w != 2 {
+			t.Errorf("%s: width of the first image = %d, want 2", tt.name, w)
+		}
+		if w, _ := img2.Size(); w != 4 {
+			t.Errorf("%s: width of the second image = %d, want 4", tt.name, w)
+		}
+	}
+	if got := a.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
+
+func TestLoadImageUnsupportedPNG(t *testing.T) {
+	// A grayscale PNG is not supported by the decoder.
+	img := image.NewGray(image.Rect(0, 0, 4, 4))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"gray.png": &fstest.MapFile{Data: buf.Bytes()}}
+	if _, err := koebiten.LoadImage(fsys, "gray.png"); !errors.Is(err, koebiten.ErrInvalidPNG) {
+		t.Errorf("got %v, want ErrInvalidPNG", err)
+	}
+}
+
+func TestAssetsConcurrent(t *testing.T) {
+	fsys := fstest.MapFS{"a.png": pngFile(t, 16, 8)}
+	a := koebiten.NewAssets(32)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 16 {
+				if _, err := a.Load(fsys, "a.png"); err != nil {
+					t.Error(err)
+					return
+				}
+				a.Release(fsys, "a.png")
+				a.UnloadUnused()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestDrawImageFSWithError(t *testing.T) {
+	fsys := fstest.MapFS{"a.png": pngFile(t, 16, 8)}
+	cache := koebiten.ImageFSAssets()
+	budget := cache.Budget()
+	t.Cleanup(func() {
+		cache.SetBudget(budget)
+		cache.UnloadUnused()
+	})
+
+	dst := koebiten.NewImage(16, 8)
+	if err := koebiten.DrawImageFSWithError(dst, fsys, "missing.png", koebiten.DrawImageFSOptions{}); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing file: got %v, want fs.ErrNotExist", err)
+	}
+
+	// An image larger than the budget is drawn without being cached.
+	cache.SetBudget(8)
+	cache.UnloadUnused()
+	if err := koebiten.DrawImageFSWithError(dst, fsys, "a.png", koebiten.DrawImageFSOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _, _, _ := dst.At(0, 0).RGBA(); r == 0 {
+		t.Errorf("image is not drawn")
+	}
+	if got := cache.Len(); got != 0 {
+		t.Errorf("Len() = %d, want 0", got)
+	}
+}
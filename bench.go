@@ -2,6 +2,7 @@
 
 package koebiten
 
+// With the koebiten_benchmark tag, RunGame prints the frame statistics every 32 ticks.
 func init() {
 	enableBenchmark = true
 }
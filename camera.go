@@ -0,0 +1,319 @@
+package koebiten
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/chewxy/math32"
+)
+
+// Camera is a view of a world that is larger than the screen.
+//
+// The position of the camera is the point of the world shown at the center of
+// the viewport. Draw the world with the geometry matrix returned by GeoM, or
+// through the Displayer returned by Displayer.
+//
+// Call Update once per tick, usually from Game.Update, to run the screen
+// shake.
+type Camera struct {
+	width, height float32
+
+	x, y     float32
+	zoom     float32
+	rotation float32
+
+	deadzoneW, deadzoneH float32
+	bounds               image.Rectangle
+
+	shakeIntensity float32
+	shakeDuration  int
+	shakeTicks     int
+	shakeX, shakeY float32
+	random         uint32
+}
+
+// NewCamera creates a Camera for a viewport of width x height pixels,
+// usually the size of the screen. The camera looks at the center of the
+// viewport in the world, without zoom or rotation.
+func NewCamera(width, height int) *Camera {
+	return &Camera{
+		width:  float32(width),
+		height: float32(height),
+		x:      float32(width) / 2,
+		y:      float32(height) / 2,
+		zoom:   1,
+		random: 0x9E3779B9,
+	}
+}
+
+// Viewport returns the size of the viewport.
+func (c *Camera) Viewport() (int, int) {
+	return int(c.width), int(c.height)
+}
+
+// SetViewport sets the size of the viewport.
+func (c *Camera) SetViewport(width, height int) {
+	c.width, c.height = float32(width), float32(height)
+	c.clamp()
+}
+
+// Position returns the point of the world at the center of the viewport.
+func (c *Camera) Position() (float32, float32) {
+	return c.x, c.y
+}
+
+// SetPosition sets the point of the world at the center of the viewport.
+// The position is clamped to the bounds.
+func (c *Camera) SetPosition(x, y float32) {
+	c.x, c.y = x, y
+	c.clamp()
+}
+
+// Move moves the camera by (dx, dy) in the world.
+func (c *Camera) Move(dx, dy float32) {
+	c.SetPosition(c.x+dx, c.y+dy)
+}
+
+// Zoom returns the zoom factor. 2 means that the world is drawn twice as
+// large.
+func (c *Camera) Zoom() float32 {
+	return c.zoom
+}
+
+// SetZoom sets the zoom factor. zoom must be positive.
+func (c *Camera) SetZoom(zoom float32) {
+	if zoom <= 0 {
+		panic("koebiten: zoom must be positive")
+	}
+	c.zoom = zoom
+	c.clamp()
+}
+
+// Rotation returns the rotation of the camera in radian.
+func (c *Camera) Rotation() float32 {
+	return c.rotation
+}
+
+// SetRotation sets the rotation of the camera in radian. The world turns the
+// other way on the screen.
+func (c *Camera) SetRotation(theta float32) {
+	c.rotation = theta
+}
+
+// SetDeadzone sets the size of the deadzone in the pixels of the viewport.
+// The deadzone is a rectangle at the center of the viewport in which the
+// target of Follow can move without moving the camera.
+// The default (zero) value is 0x0, which keeps the target at the center.
+func (c *Camera) SetDeadzone(width, height int) {
+	c.deadzoneW, c.deadzoneH = float32(width), float32(height)
+}
+
+// Follow moves the camera just enough to keep the point (x, y) of the world in
+// the deadzone. Call it every tick with the position of the player.
+func (c *Camera) Follow(x, y float32) {
+	hw, hh := c.deadzoneW/2/c.zoom, c.deadzoneH/2/c.zoom
+	nx, ny := c.x, c.y
+	if x < nx-hw {
+		nx = x + hw
+	} else if x > nx+hw {
+		nx = x - hw
+	}
+	if y < ny-hh {
+		ny = y + hh
+	} else if y > ny+hh {
+		ny = y - hh
+	}
+	c.SetPosition(nx, ny)
+}
+
+// SetBounds sets the area of the world the camera is kept in, so that the
+// viewport doesn't show what is outside. If the area is smaller than the
+// viewport, the camera is centered on it. The rotation is not taken into
+// account.
+// The default (zero) value is an empty rectangle, which means no bounds.
+func (c *Camera) SetBounds(bounds image.Rectangle) {
+	c.bounds = bounds
+	c.clamp()
+}
+
+// clamp keeps the position in the bounds.
+func (c *Camera) clamp() {
+	if c.bounds.Empty() {
+		return
+	}
+	hw, hh := c.width/2/c.zoom, c.height/2/c.zoom
+	c.x = clampView(c.x, hw, float32(c.bounds.Min.X), float32(c.bounds.Max.X))
+	c.y = clampView(c.y, hh, float32(c.bounds.Min.Y), float32(c.bounds.Max.Y))
+}
+
+// clampView clamps the center v of a view of half size h to [lo, hi].
+func clampView(v, h, lo, hi float32) float32 {
+	if hi-lo <= 2*h {
+		return (lo + hi) / 2
+	}
+	return math32.Min(math32.Max(v, lo+h), hi-h)
+}
+
+// Shake shakes the view for duration ticks. The view moves randomly by up to
+// intensity pixels of the viewport, decreasing to 0 at the end.
+func (c *Camera) Shake(intensity float32, duration int) {
+	c.shakeIntensity = intensity
+	c.shakeDuration = duration
+	c.shakeTicks = duration
+}
+
+// IsShaking reports whether the view is shaking.
+func (c *Camera) IsShaking() bool {
+	return c.shakeTicks > 0
+}
+
+// Update advances the screen shake by one tick.
+func (c *Camera) Update() {
+	if c.shakeTicks <= 0 {
+		c.shakeX, c.shakeY = 0, 0
+		return
+	}
+	amp := c.shakeIntensity * float32(c.shakeTicks) / float32(c.shakeDuration)
+	c.shakeX = amp * c.nextRandom()
+	c.shakeY = amp * c.nextRandom()
+	c.shakeTicks--
+}
+
+// nextRandom returns a pseudo-random number in [-1, 1). A fixed sequence is
+// used, so that replayed input gives the same frames.
+func (c *Camera) nextRandom() float32 {
+	// xorshift32
+	c.random ^= c.random << 13
+	c.random ^= c.random >> 17
+	c.random ^= c.random << 5
+	return float32(c.random>>8)/(1<<23) - 1
+}
+
+// GeoM returns the geometry matrix from the world to the viewport.
+//
+// Concatenate it to the GeoM of DrawImageOptions to draw an image placed in
+// the world:
+//
+//	op := koebiten.DrawImageOptions{}
+//	op.GeoM.Translate(playerX, playerY)
+//	op.GeoM.Concat(camera.GeoM())
+//	player.DrawImage(screen, op)
+func (c *Camera) GeoM() GeoM {
+	var g GeoM
+	g.Translate(-c.x, -c.y)
+	g.Rotate(-c.rotation)
+	g.Scale(c.zoom, c.zoom)
+	g.Translate(c.width/2+c.shakeX, c.height/2+c.shakeY)
+	return g
+}
+
+// WorldToScreen converts a point of the world to the viewport.
+func (c *Camera) WorldToScreen(x, y float32) (float32, float32) {
+	g := c.GeoM()
+	return g.Apply(x, y)
+}
+
+// ScreenToWorld converts a point of the viewport to the world.
+func (c *Camera) ScreenToWorld(x, y float32) (float32, float32) {
+	g := c.GeoM()
+	g.Invert()
+	return g.Apply(x, y)
+}
+
+// Displayer returns a Displayer that draws to dst in the coordinates of the
+// world, with the current state of the camera. Pass it to the Draw*
+// functions to draw primitives placed in the world:
+//
+//	koebiten.DrawFilledRect(camera.Displayer(screen), enemyX, enemyY, 8, 8, white)
+//
+// Each pixel of the world is drawn as the pixels of dst it covers, so the
+// primitives are scaled and rotated with the world. If dst is nil, the
+// display is used.
+func (c *Camera) Displayer(dst Displayer) Displayer {
+	d := &cameraDisplay{
+		dst:  dst,
+		geoM: c.GeoM(),
+	}
+	d.inv = d.geoM
+	d.inv.Invert()
+	a, b, cc, dd, _, _ := d.geoM.elements32()
+	d.translate = a == 1 && b == 0 && cc == 0 && dd == 1
+	return d
+}
+
+// cameraDisplay draws to a Displayer through the geometry matrix of a camera.
+type cameraDisplay struct {
+	dst       Displayer
+	geoM      GeoM
+	inv       GeoM
+	translate bool
+}
+
+func (d *cameraDisplay) target() Displayer {
+	if isNil(d.dst) {
+		return display
+	}
+	return d.dst
+}
+
+// Size returns the size of the destination.
+func (d *cameraDisplay) Size() (int16, int16) {
+	return d.target().Size()
+}
+
+// SetPixel fills the pixels of the destination covered by the pixel (x, y)
+// of the world.
+func (d *cameraDisplay) SetPixel(x, y int16, clr color.RGBA) {
+	dst := d.target()
+	if d.translate {
+		// Like DrawImage, draw at the rounded position.
+		tx, ty := d.geoM.Apply(float32(x), float32(y))
+		dst.SetPixel(int16(math32.Round(tx)), int16(math32.Round(ty)), clr)
+		return
+	}
+
+	minX, minY := math32.Inf(1), math32.Inf(1)
+	maxX, maxY := math32.Inf(-1), math32.Inf(-1)
+	for _, p := range [4][2]float32{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+		sx, sy := d.geoM.Apply(float32(x)+p[0], float32(y)+p[1])
+		minX, maxX = math32.Min(minX, sx), math32.Max(maxX, sx)
+		minY, maxY = math32.Min(minY, sy), math32.Max(maxY, sy)
+	}
+	w, h := dst.Size()
+	for sy := max(int(math32.Floor(minY)), 0); sy < min(int(math32.Ceil(maxY)), int(h)); sy++ {
+		for sx := max(int(math32.Floor(minX)), 0); sx < min(int(math32.Ceil(maxX)), int(w)); sx++ {
+			// Fill the pixels whose center is in the pixel of the world.
+			wx, wy := d.inv.Apply(float32(sx)+0.5, float32(sy)+0.5)
+			if int16(math32.Floor(wx)) == x && int16(math32.Floor(wy)) == y {
+				dst.SetPixel(int16(sx), int16(sy), clr)
+			}
+		}
+	}
+}
+
+// PixelAt returns the color of the pixel of the destination at the center of
+// the pixel (x, y) of the world. It returns black if the destination doesn't
+// implement PixelReader.
+func (d *cameraDisplay) PixelAt(x, y int16) color.RGBA {
+	r, ok := d.target().(PixelReader)
+	if !ok {
+		return black
+	}
+	sx, sy := d.geoM.Apply(float32(x)+0.5, float32(y)+0.5)
+	return r.PixelAt(int16(math32.Floor(sx)), int16(math32.Floor(sy)))
+}
+
+// Display calls Display of the destination.
+func (d *cameraDisplay) Display() error {
+	return d.target().Display()
+}
+
+// ClearDisplay calls ClearDisplay of the destination.
+func (d *cameraDisplay) ClearDisplay() {
+	d.target().ClearDisplay()
+}
+
+// ClearBuffer calls ClearBuffer of the destination.
+func (d *cameraDisplay) ClearBuffer() {
+	d.target().ClearBuffer()
+}
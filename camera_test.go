@@ -0,0 +1,96 @@
+package koebiten_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+)
+
+func TestCameraFollow(t *testing.T) {
+	c := koebiten.NewCamera(100, 50)
+	c.SetDeadzone(20, 10)
+	c.SetPosition(100, 100)
+
+	// The target moves in the deadzone.
+	c.Follow(108, 96)
+	if x, y := c.Position(); x != 100 || y != 100 {
+		t.Errorf("Position() = %v, %v, want 100, 100", x, y)
+	}
+	// The target leaves the deadzone on the right and at the top.
+	c.Follow(115, 90)
+	if x, y := c.Position(); x != 105 || y != 95 {
+		t.Errorf("Position() = %v, %v, want 105, 95", x, y)
+	}
+
+	// The view is kept in the bounds.
+	c.SetBounds(image.Rect(0, 0, 400, 40))
+	c.Follow(390, 0)
+	if x, y := c.Position(); x != 350 || y != 20 {
+		t.Errorf("Position() in the bounds = %v, %v, want 350, 20", x, y)
+	}
+}
+
+func TestCameraTransform(t *testing.T) {
+	c := koebiten.NewCamera(100, 50)
+	c.SetPosition(200, 100)
+	c.SetZoom(2)
+	if x, y := c.WorldToScreen(210, 100); x != 70 || y != 25 {
+		t.Errorf("WorldToScreen() = %v, %v, want 70, 25", x, y)
+	}
+
+	c.SetRotation(math32.Pi / 2)
+	x, y := c.WorldToScreen(210, 100)
+	if math32.Abs(x-50) > 1e-3 || math32.Abs(y-5) > 1e-3 {
+		t.Errorf("rotated WorldToScreen() = %v, %v, want 50, 5", x, y)
+	}
+	x, y = c.ScreenToWorld(x, y)
+	if math32.Abs(x-210) > 1e-3 || math32.Abs(y-100) > 1e-3 {
+		t.Errorf("ScreenToWorld() = %v, %v, want 210, 100", x, y)
+	}
+}
+
+func TestCameraShake(t *testing.T) {
+	c := koebiten.NewCamera(100, 50)
+	c.Shake(4, 10)
+	moved := false
+	for i := 0; i < 10; i++ {
+		c.Update()
+		x, y := c.WorldToScreen(50, 25)
+		if math32.Abs(x-50) > 4 || math32.Abs(y-25) > 4 {
+			t.Fatalf("shake offset = %v, %v, want at most 4", x-50, y-25)
+		}
+		moved = moved || x != 50 || y != 25
+	}
+	if !moved {
+		t.Errorf("the view didn't shake")
+	}
+	c.Update()
+	if c.IsShaking() {
+		t.Errorf("IsShaking() = true after the duration")
+	}
+	if x, y := c.WorldToScreen(50, 25); x != 50 || y != 25 {
+		t.Errorf("WorldToScreen() after the shake = %v, %v, want 50, 25", x, y)
+	}
+}
+
+func TestCameraDisplayer(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	screen := koebiten.NewImage(16, 16)
+	c := koebiten.NewCamera(16, 16)
+	c.SetPosition(100, 100)
+	c.SetZoom(2)
+
+	// The pixel (101, 100) of the world covers 2x2 pixels of the screen.
+	c.Displayer(screen).SetPixel(101, 100, white)
+	for y := int16(0); y < 16; y++ {
+		for x := int16(0); x < 16; x++ {
+			want := (x == 10 || x == 11) && (y == 8 || y == 9)
+			if got := screen.PixelAt(x, y) == white; got != want {
+				t.Errorf("pixel (%d, %d) set = %v, want %v", x, y, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package koebiten
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// ErrScreenCaptureDisabled is returned when a frame is requested but the
+// display can't be read back and screen capture is not enabled.
+var ErrScreenCaptureDisabled = errors.New("koebiten: screen capture is disabled")
+
+// FrameImager is implemented by displays that keep the last presented frame
+// in memory, such as the headless display of the host build.
+type FrameImager interface {
+	// Image returns the last frame presented by Display.
+	Image() *image.RGBA
+}
+
+var (
+	theCapture *captureDisplay
+
+	screenshotKeys []Key
+	screenshotFunc func(img *image.RGBA)
+	screenshotReq  bool
+)
+
+// SetScreenCaptureEnabled enables or disables screen capture.
+//
+// When enabled, every pixel drawn to the screen is mirrored into an in-memory
+// frame, so that Screenshot and RecordGIF work with any Displayer. The frame
+// takes 4 bytes per pixel twice, so it is disabled by default.
+func SetScreenCaptureEnabled(enabled bool) {
+	if enabled {
+		if theCapture == nil {
+			theCapture = &captureDisplay{}
+		}
+	} else {
+		theCapture = nil
+	}
+	setupDisplay()
+}
+
+// Screenshot returns a copy of the last frame presented on the screen.
+//
+// If screen capture is disabled, the frame is read from the display when it
+// implements FrameImager. Otherwise ErrScreenCaptureDisabled is returned.
+func Screenshot() (*image.RGBA, error) {
+	src := currentFrame()
+	if src == nil {
+		return nil, ErrScreenCaptureDisabled
+	}
+	img := image.NewRGBA(src.Bounds())
+	copy(img.Pix, src.Pix)
+	return img, nil
+}
+
+// SetScreenshotHotkey registers a key combination that takes a screenshot.
+// When all the keys are pressed and one of them has just been pressed, fn is
+// called with the next frame presented on the screen.
+//
+// Calling SetScreenshotHotkey with no keys removes the hotkey.
+func SetScreenshotHotkey(fn func(img *image.RGBA), keys ...Key) {
+	screenshotKeys = append(screenshotKeys[:0], keys...)
+	screenshotFunc = fn
+	screenshotReq = false
+}
+
+// currentFrame returns the last presented frame, or nil if it is not available.
+func currentFrame() *image.RGBA {
+	if theCapture != nil && theCapture.front != nil {
+		return theCapture.front
+	}
+	if f, ok := deviceDisplay.(FrameImager); ok {
+		return f.Image()
+	}
+	return nil
+}
+
+// checkScreenshotHotkey is called by the main loop after the input is updated.
+func checkScreenshotHotkey() {
+	if len(screenshotKeys) == 0 || screenshotFunc == nil {
+		return
+	}
+	just := false
+	for _, k := range screenshotKeys {
+		if !IsKeyPressed(k) {
+			return
+		}
+		if IsKeyJustPressed(k) {
+			just = true
+		}
+	}
+	if just {
+		screenshotReq = true
+	}
+}
+
+// afterDisplay is called by the main loop after a frame is presented.
+func afterDisplay() {
+	if screenshotReq {
+		screenshotReq = false
+		if img, err := Screenshot(); err == nil {
+			screenshotFunc(img)
+		}
+	}
+
+	recordGIFFrames()
+}
+
+// captureDisplay mirrors the pixels drawn to a Displayer into an image.
+type captureDisplay struct {
+	Displayer
+	back  *image.RGBA
+	front *image.RGBA
+}
+
+func (d *captureDisplay) setDisplayer(dst Displayer) {
+	d.Displayer = dst
+	w, h := dst.Size()
+	if d.back == nil || d.back.Bounds().Dx() != int(w) || d.back.Bounds().Dy() != int(h) {
+		d.back = image.NewRGBA(image.Rect(0, 0, int(w), int(h)))
+		d.front = nil
+		d.ClearBuffer()
+	}
+}
+
+func (d *captureDisplay) SetPixel(x, y int16, c color.RGBA) {
+	d.Displayer.SetPixel(x, y, c)
+	d.back.SetRGBA(int(x), int(y), c)
+}
+
+func (d *captureDisplay) PixelAt(x, y int16) color.RGBA {
+	if !(image.Point{int(x), int(y)}.In(d.back.Rect)) {
+		return black
+	}
+	return d.back.RGBAAt(int(x), int(y))
+}
+
+func (d *captureDisplay) Display() error {
+	if d.front == nil {
+		d.front = image.NewRGBA(d.back.Bounds())
+	}
+	copy(d.front.Pix, d.back.Pix)
+	return d.Displayer.Display()
+}
+
+func (d *captureDisplay) ClearBuffer() {
+	d.Displayer.ClearBuffer()
+	draw.Draw(d.back, d.back.Bounds(), image.NewUniform(black), image.Point{}, draw.Src)
+}
@@ -0,0 +1,177 @@
+//go:build !tinygo
+
+package koebiten
+
+// The encoders of PNG and GIF images are only built on the host, so that
+// their codecs are not linked into the firmware of the games.
+
+import (
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+)
+
+var gifRecorders []*GIFRecorder
+
+// SaveScreenshot encodes the last frame presented on the screen to w as PNG.
+func SaveScreenshot(w io.Writer) error {
+	img, err := Screenshot()
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
+
+// recordGIFFrames adds the last presented frame to the running GIF
+// recorders. It is called by the main loop after a frame is presented.
+func recordGIFFrames() {
+	if len(gifRecorders) == 0 {
+		return
+	}
+	frame := currentFrame()
+	recorders := gifRecorders[:0]
+	for _, r := range gifRecorders {
+		if frame == nil {
+			r.finish(ErrScreenCaptureDisabled)
+			continue
+		}
+		r.add(frame)
+		if !r.Done() {
+			recorders = append(recorders, r)
+		}
+	}
+	gifRecorders = recorders
+}
+
+// GIFRecorder records the frames presented on the screen as an animated GIF.
+type GIFRecorder struct {
+	w      io.Writer
+	n      int
+	frames []*image.Paletted
+	ticks  []uint64
+	done   bool
+	err    error
+}
+
+// RecordGIF starts recording the next n frames presented on the screen.
+// When n frames are recorded, they are encoded to w as an animated GIF whose
+// frame delays follow the TPS of the game.
+//
+// Screen capture must be enabled with SetScreenCaptureEnabled, unless the
+// display implements FrameImager. Every frame is kept in memory until the GIF
+// is encoded, so it is meant for the host build or for short clips.
+func RecordGIF(w io.Writer, n int) *GIFRecorder {
+	r := &GIFRecorder{
+		w: w,
+		n: n,
+	}
+	if n <= 0 {
+		r.finish(nil)
+		return r
+	}
+	gifRecorders = append(gifRecorders, r)
+	return r
+}
+
+// Done reports whether the recording is finished.
+func (r *GIFRecorder) Done() bool {
+	return r.done
+}
+
+// Err returns the error that occurred while recording or encoding, if any.
+func (r *GIFRecorder) Err() error {
+	return r.err
+}
+
+// Stop finishes the recording early and encodes the frames recorded so far.
+func (r *GIFRecorder) Stop() error {
+	if !r.done {
+		for i, rr := range gifRecorders {
+			if rr == r {
+				gifRecorders = append(gifRecorders[:i], gifRecorders[i+1:]...)
+				break
+			}
+		}
+		r.finish(nil)
+	}
+	return r.err
+}
+
+func (r *GIFRecorder) add(frame *image.RGBA) {
+	r.frames = append(r.frames, toPaletted(frame))
+	r.ticks = append(r.ticks, theFrameStats.numTicks())
+	if len(r.frames) >= r.n {
+		r.finish(nil)
+	}
+}
+
+func (r *GIFRecorder) finish(err error) {
+	r.done = true
+	r.err = err
+	if err != nil || len(r.frames) == 0 {
+		return
+	}
+
+	tps := TPS()
+	if tps <= 0 {
+		tps = DefaultTPS
+	}
+	g := &gif.GIF{
+		Image: r.frames,
+		Delay: make([]int, len(r.frames)),
+	}
+	// Delays are in 100ths of a second. The rounding error is carried over
+	// to the next frame so that the total length stays accurate.
+	carry := 0
+	for i := range r.frames {
+		ticks := 1
+		if i+1 < len(r.ticks) {
+			ticks = int(r.ticks[i+1] - r.ticks[i])
+		} else if i > 0 {
+			ticks = int(r.ticks[i] - r.ticks[i-1])
+		}
+		cs := ticks*100 + carry
+		g.Delay[i] = cs / tps
+		carry = cs % tps
+	}
+	r.err = gif.EncodeAll(r.w, g)
+	r.frames = nil
+}
+
+// toPaletted converts a frame to a paletted image. The palette is made of the
+// colors of the frame if there are few enough of them, as on monochrome and
+// grayscale displays.
+func toPaletted(src *image.RGBA) *image.Paletted {
+	b := src.Bounds()
+	var p color.Palette
+	index := map[color.RGBA]uint8{}
+	for i := 0; i < len(src.Pix); i += 4 {
+		c := color.RGBA{R: src.Pix[i], G: src.Pix[i+1], B: src.Pix[i+2], A: src.Pix[i+3]}
+		if _, ok := index[c]; ok {
+			continue
+		}
+		if len(p) == 256 {
+			p = nil
+			break
+		}
+		index[c] = uint8(len(p))
+		p = append(p, c)
+	}
+
+	if p == nil {
+		dst := image.NewPaletted(b, palette.Plan9)
+		draw.Draw(dst, b, src, b.Min, draw.Src)
+		return dst
+	}
+	dst := image.NewPaletted(b, p)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			dst.SetColorIndex(x, y, index[src.RGBAAt(x, y)])
+		}
+	}
+	return dst
+}
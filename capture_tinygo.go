@@ -0,0 +1,6 @@
+//go:build tinygo
+
+package koebiten
+
+// recordGIFFrames does nothing: GIF recording is only built on the host.
+func recordGIFFrames() {}
@@ -0,0 +1,87 @@
+// Package collision provides collision tests between shapes and between the
+// pixels of koebiten images.
+//
+// Positions are in pixels, like the coordinates of the display. Rectangles
+// are image.Rectangle values, whose Max point is excluded.
+package collision
+
+import (
+	"image"
+)
+
+// Rects reports whether a and b overlap, and returns their overlap.
+func Rects(a, b image.Rectangle) (image.Rectangle, bool) {
+	r := a.Intersect(b)
+	return r, !r.Empty()
+}
+
+// Circle is a circle centered on the pixel (X, Y). The pixels whose distance
+// to the center is at most R are in the circle.
+type Circle struct {
+	X, Y, R int
+}
+
+// Bounds returns the smallest rectangle that contains the circle.
+func (c Circle) Bounds() image.Rectangle {
+	return image.Rect(c.X-c.R, c.Y-c.R, c.X+c.R+1, c.Y+c.R+1)
+}
+
+// Contains reports whether the pixel (x, y) is in the circle.
+func (c Circle) Contains(x, y int) bool {
+	dx, dy := x-c.X, y-c.Y
+	return dx*dx+dy*dy <= c.R*c.R
+}
+
+// Circles reports whether a and b overlap.
+func Circles(a, b Circle) bool {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	r := a.R + b.R
+	return dx*dx+dy*dy <= r*r
+}
+
+// CircleRect reports whether c and r overlap, and returns the overlap of r
+// and the bounds of c.
+func CircleRect(c Circle, r image.Rectangle) (image.Rectangle, bool) {
+	if r.Empty() {
+		return image.Rectangle{}, false
+	}
+	// The pixel of r nearest to the center of the circle.
+	x := min(max(c.X, r.Min.X), r.Max.X-1)
+	y := min(max(c.Y, r.Min.Y), r.Max.Y-1)
+	if !c.Contains(x, y) {
+		return image.Rectangle{}, false
+	}
+	return r.Intersect(c.Bounds()), true
+}
+
+// PointInRect reports whether the pixel (x, y) is in r.
+func PointInRect(x, y int, r image.Rectangle) bool {
+	return image.Pt(x, y).In(r)
+}
+
+// PointInCircle reports whether the pixel (x, y) is in c.
+func PointInCircle(x, y int, c Circle) bool {
+	return c.Contains(x, y)
+}
+
+// PointInPolygon reports whether the pixel (x, y) is in the polygon whose
+// vertices are points, with the even-odd rule. The center of the pixel is
+// tested, so the pixels of the left and top edges are in the polygon, and
+// those of the right and bottom edges are not.
+func PointInPolygon(x, y int, points []image.Point) bool {
+	// Test the center of the pixel, in half pixels.
+	px, py := 2*x+1, 2*y+1
+	in := false
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		ax, ay := 2*points[i].X, 2*points[i].Y
+		bx, by := 2*points[j].X, 2*points[j].Y
+		if (ay > py) == (by > py) {
+			continue
+		}
+		// The x of the edge at py, compared without division.
+		if (px-ax)*(by-ay) < (bx-ax)*(py-ay) == (by > ay) {
+			in = !in
+		}
+	}
+	return in
+}
@@ -0,0 +1,106 @@
+package collision_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+	"github.com/sago35/koebiten/collision"
+)
+
+func TestShapes(t *testing.T) {
+	if r, ok := collision.Rects(image.Rect(0, 0, 10, 10), image.Rect(5, 8, 20, 20)); !ok || r != image.Rect(5, 8, 10, 10) {
+		t.Errorf("Rects() = %v, %v, want (5,8)-(10,10), true", r, ok)
+	}
+	if _, ok := collision.Rects(image.Rect(0, 0, 10, 10), image.Rect(10, 0, 20, 10)); ok {
+		t.Errorf("Rects() of touching rectangles = true, want false")
+	}
+
+	if !collision.Circles(collision.Circle{X: 0, Y: 0, R: 3}, collision.Circle{X: 5, Y: 0, R: 2}) {
+		t.Errorf("Circles() of touching circles = false, want true")
+	}
+	if collision.Circles(collision.Circle{X: 0, Y: 0, R: 3}, collision.Circle{X: 5, Y: 5, R: 2}) {
+		t.Errorf("Circles() of distant circles = true, want false")
+	}
+
+	c := collision.Circle{X: 10, Y: 10, R: 3}
+	if r, ok := collision.CircleRect(c, image.Rect(12, 0, 20, 20)); !ok || r != image.Rect(12, 7, 14, 14) {
+		t.Errorf("CircleRect() = %v, %v, want (12,7)-(14,14), true", r, ok)
+	}
+	// The corner of the rectangle is out of the circle.
+	if _, ok := collision.CircleRect(c, image.Rect(13, 13, 20, 20)); ok {
+		t.Errorf("CircleRect() at the corner = true, want false")
+	}
+
+	triangle := []image.Point{{0, 0}, {10, 0}, {0, 10}}
+	for _, tc := range []struct {
+		x, y int
+		want bool
+	}{
+		{0, 0, true},
+		{4, 4, true},
+		{5, 5, false},
+		{9, 0, true},
+		{10, 0, false},
+		{-1, 3, false},
+	} {
+		if got := collision.PointInPolygon(tc.x, tc.y, triangle); got != tc.want {
+			t.Errorf("PointInPolygon(%d, %d) = %v, want %v", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+func TestMasks(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+
+	// A horizontal bar of 6x2 pixels in an 8x8 image.
+	bar := koebiten.NewImage(8, 8)
+	for x := int16(1); x < 7; x++ {
+		bar.SetPixel(x, 3, white)
+		bar.SetPixel(x, 4, white)
+	}
+	mask := collision.NewMask(bar)
+
+	var ga, gb koebiten.GeoM
+	ga.Translate(10, 10)
+	gb.Translate(13, 11.4)
+	if r, ok := collision.Masks(mask, ga, mask, gb); !ok || r != image.Rect(14, 14, 17, 15) {
+		t.Errorf("Masks() = %v, %v, want (14,14)-(17,15), true", r, ok)
+	}
+	// The images overlap, but not their bars.
+	gb.Reset()
+	gb.Translate(10, 13)
+	if _, ok := collision.Masks(mask, ga, mask, gb); ok {
+		t.Errorf("Masks() of separate bars = true, want false")
+	}
+
+	// A vertical bar drawn by rotating the horizontal one around its center
+	// crosses the other one.
+	gb.Reset()
+	gb.Translate(-4, -4)
+	gb.Rotate(math32.Pi / 2)
+	gb.Translate(14, 14)
+	r, ok := collision.Images(bar, ga, bar, gb)
+	if !ok {
+		t.Fatalf("Masks() of crossing bars = false, want true")
+	}
+
+	// The overlap is where both images are drawn.
+	screen := koebiten.NewImage(32, 32)
+	bar.DrawImage(screen, koebiten.DrawImageOptions{GeoM: ga})
+	other := koebiten.NewImage(32, 32)
+	bar.DrawImage(other, koebiten.DrawImageOptions{GeoM: gb})
+	var want image.Rectangle
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			if screen.PixelAt(int16(x), int16(y)) == white && other.PixelAt(int16(x), int16(y)) == white {
+				want = want.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	if r != want {
+		t.Errorf("Masks() = %v, want %v", r, want)
+	}
+}
@@ -0,0 +1,146 @@
+package collision
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+)
+
+// Mask is the set of solid pixels of an image, one bit per pixel.
+//
+// Build a Mask once for each sprite and reuse it, instead of calling Images
+// every tick.
+type Mask struct {
+	width  int
+	height int
+	stride int
+	bits   []byte
+}
+
+// NewMask creates a Mask of img whose solid pixels are those that are not
+// black and not transparent. For a monochrome image, they are the set pixels,
+// which are the pixels drawn by DrawImage by default.
+func NewMask(img *koebiten.Image) *Mask {
+	return NewMaskWithColorKey(img, color.Black)
+}
+
+// NewMaskWithColorKey creates a Mask of img whose solid pixels are those that
+// are not the color key and not transparent, like the pixels drawn by
+// DrawImage with the same ColorKey option.
+func NewMaskWithColorKey(img *koebiten.Image, key color.Color) *Mask {
+	k := color.RGBAModel.Convert(key).(color.RGBA)
+	w, h := img.Size()
+	m := &Mask{
+		width:  int(w),
+		height: int(h),
+		stride: (int(w) + 7) / 8,
+	}
+	m.bits = make([]byte, m.stride*m.height)
+	for y := 0; y < m.height; y++ {
+		for x := 0; x < m.width; x++ {
+			c := img.PixelAt(int16(x), int16(y))
+			if c.A == 0 || (c.R == k.R && c.G == k.G && c.B == k.B) {
+				continue
+			}
+			m.bits[y*m.stride+x/8] |= 0x80 >> (x % 8)
+		}
+	}
+	return m
+}
+
+// Size returns the size of the mask.
+func (m *Mask) Size() (int, int) {
+	return m.width, m.height
+}
+
+// At reports whether the pixel (x, y) of the mask is solid. The pixels out of
+// the mask are not solid.
+func (m *Mask) At(x, y int) bool {
+	if x < 0 || m.width <= x || y < 0 || m.height <= y {
+		return false
+	}
+	return m.bits[y*m.stride+x/8]&(0x80>>(x%8)) != 0
+}
+
+// Images reports whether the solid pixels of a and b overlap when they are
+// drawn with the geometry matrices ga and gb, and returns the smallest
+// rectangle that contains the overlapping pixels. It builds the masks with
+// NewMask on every call.
+func Images(a *koebiten.Image, ga koebiten.GeoM, b *koebiten.Image, gb koebiten.GeoM) (image.Rectangle, bool) {
+	return Masks(NewMask(a), ga, NewMask(b), gb)
+}
+
+// Masks reports whether the solid pixels of a and b overlap when they are
+// placed with the geometry matrices ga and gb, and returns the smallest
+// rectangle that contains the overlapping pixels.
+//
+// The pixels are placed as DrawImage draws them with FilterNearest, so
+// scaled and rotated masks collide where their images are drawn.
+func Masks(a *Mask, ga koebiten.GeoM, b *Mask, gb koebiten.GeoM) (image.Rectangle, bool) {
+	if !ga.IsInvertible() || !gb.IsInvertible() {
+		return image.Rectangle{}, false
+	}
+	pa, pb := newPlacement(a, ga), newPlacement(b, gb)
+	area := pa.bounds.Intersect(pb.bounds)
+
+	var overlap image.Rectangle
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if !pa.at(x, y) || !pb.at(x, y) {
+				continue
+			}
+			overlap = overlap.Union(image.Rect(x, y, x+1, y+1))
+		}
+	}
+	return overlap, !overlap.Empty()
+}
+
+// placement is a mask placed on the display with a geometry matrix.
+type placement struct {
+	mask   *Mask
+	bounds image.Rectangle
+
+	// The translation of the mask if the matrix only translates it.
+	translate bool
+	ox, oy    int
+
+	// The inverted matrix.
+	inv koebiten.GeoM
+}
+
+func newPlacement(m *Mask, g koebiten.GeoM) placement {
+	p := placement{mask: m}
+	if g.Element(0, 0) == 1 && g.Element(0, 1) == 0 && g.Element(1, 0) == 0 && g.Element(1, 1) == 1 {
+		// DrawImage draws the image at the rounded position.
+		tx, ty := g.Apply(0, 0)
+		p.translate = true
+		p.ox, p.oy = int(math32.Round(tx)), int(math32.Round(ty))
+		p.bounds = image.Rect(0, 0, m.width, m.height).Add(image.Pt(p.ox, p.oy))
+		return p
+	}
+
+	minX, minY := math32.Inf(1), math32.Inf(1)
+	maxX, maxY := math32.Inf(-1), math32.Inf(-1)
+	for _, c := range [4][2]float32{{0, 0}, {float32(m.width), 0}, {0, float32(m.height)}, {float32(m.width), float32(m.height)}} {
+		x, y := g.Apply(c[0], c[1])
+		minX, maxX = math32.Min(minX, x), math32.Max(maxX, x)
+		minY, maxY = math32.Min(minY, y), math32.Max(maxY, y)
+	}
+	p.bounds = image.Rect(int(math32.Floor(minX)), int(math32.Floor(minY)), int(math32.Ceil(maxX)), int(math32.Ceil(maxY)))
+	p.inv = g
+	p.inv.Invert()
+	return p
+}
+
+// at reports whether the pixel (x, y) of the display is covered by a solid
+// pixel of the mask.
+func (p *placement) at(x, y int) bool {
+	if p.translate {
+		return p.mask.At(x-p.ox, y-p.oy)
+	}
+	// Sample at the center of the pixel, like FilterNearest.
+	u, v := p.inv.Apply(float32(x)+0.5, float32(y)+0.5)
+	return p.mask.At(int(math32.Floor(u)), int(math32.Floor(v)))
+}
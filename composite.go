@@ -0,0 +1,250 @@
+package koebiten
+
+import (
+	"image/color"
+)
+
+// PixelReader is implemented by Displayers whose pixels can be read back,
+// such as Image and the displays of the bundled hardware.
+//
+// Composite modes that combine the source with the destination need it.
+type PixelReader interface {
+	// PixelAt returns the color of the pixel at the given x and y coordinates
+	// in the buffer, or black if they are outside the display.
+	PixelAt(x, y int16) color.RGBA
+}
+
+// CompositeMode represents how the pixels of an image are combined with the
+// pixels of the destination in DrawImage.
+//
+// The modes other than CompositeModeSourceOver and CompositeModeCopy read
+// the destination, so the destination should implement PixelReader. If it
+// doesn't, the destination is assumed to be black.
+type CompositeMode int
+
+const (
+	// CompositeModeSourceOver draws the pixels of the source that are not
+	// transparent. Partially transparent pixels are blended with the
+	// destination. This is the default mode.
+	CompositeModeSourceOver CompositeMode = iota
+
+	// CompositeModeCopy draws every pixel of the source, including the
+	// transparent ones. For example, the cleared pixels of a monochrome
+	// image are drawn in black.
+	CompositeModeCopy
+
+	// CompositeModeOr combines the source and the destination with a bitwise OR.
+	CompositeModeOr
+
+	// CompositeModeAnd combines the source and the destination with a bitwise AND.
+	CompositeModeAnd
+
+	// CompositeModeXor combines the source and the destination with a bitwise XOR.
+	CompositeModeXor
+
+	// CompositeModeInvert inverts the destination where the source is not
+	// transparent. The color of the source is ignored.
+	CompositeModeInvert
+)
+
+// ColorScale represents a scale of RGBA color applied to every pixel of an
+// image in DrawImage.
+//
+// The initial value is identity.
+type ColorScale struct {
+	// These values are the actual values minus 1, so that the zero value is identity.
+	r_1, g_1, b_1, a_1 float32
+}
+
+// Reset resets the ColorScale as identity.
+func (c *ColorScale) Reset() {
+	c.r_1 = 0
+	c.g_1 = 0
+	c.b_1 = 0
+	c.a_1 = 0
+}
+
+// R returns the red scale.
+func (c *ColorScale) R() float32 {
+	return c.r_1 + 1
+}
+
+// G returns the green scale.
+func (c *ColorScale) G() float32 {
+	return c.g_1 + 1
+}
+
+// B returns the blue scale.
+func (c *ColorScale) B() float32 {
+	return c.b_1 + 1
+}
+
+// A returns the alpha scale.
+func (c *ColorScale) A() float32 {
+	return c.a_1 + 1
+}
+
+// Scale multiplies the color scale by the given values.
+func (c *ColorScale) Scale(r, g, b, a float32) {
+	c.r_1 = (c.r_1+1)*r - 1
+	c.g_1 = (c.g_1+1)*g - 1
+	c.b_1 = (c.b_1+1)*b - 1
+	c.a_1 = (c.a_1+1)*a - 1
+}
+
+// ScaleAlpha multiplies the alpha scale by a.
+func (c *ColorScale) ScaleAlpha(a float32) {
+	c.a_1 = (c.a_1+1)*a - 1
+}
+
+// ScaleWithColor multiplies the color scale by the given color.
+func (c *ColorScale) ScaleWithColor(clr color.Color) {
+	r, g, b, a := clr.RGBA()
+	if a == 0 {
+		c.Scale(0, 0, 0, 0)
+		return
+	}
+	// Un-premultiply the color.
+	c.Scale(float32(r)/float32(a), float32(g)/float32(a), float32(b)/float32(a), float32(a)/0xFFFF)
+}
+
+func (c *ColorScale) isIdentity() bool {
+	return c.r_1 == 0 && c.g_1 == 0 && c.b_1 == 0 && c.a_1 == 0
+}
+
+// compositor writes the pixels of a source image to a destination with the
+// options of DrawImage.
+type compositor struct {
+	dst    Displayer
+	reader PixelReader
+	mode   CompositeMode
+	scale  ColorScale
+
+	key    color.RGBA
+	hasKey bool
+	mask   *Image
+}
+
+func newCompositor(dst Displayer, src *Image, options *DrawImageOptions) compositor {
+	c := compositor{
+		dst:   dst,
+		mode:  options.CompositeMode,
+		scale: options.ColorScale,
+		mask:  options.Mask,
+	}
+	c.reader, _ = dst.(PixelReader)
+	if options.ColorKey != nil {
+		c.key = color.RGBAModel.Convert(options.ColorKey).(color.RGBA)
+		c.hasKey = true
+	} else if src.format == FormatMonochrome {
+		c.key = black
+		c.hasKey = true
+	}
+	return c
+}
+
+// draw draws the color src of the source pixel at (sx, sy) to the pixel at
+// (x, y) of the destination.
+func (c *compositor) draw(x, y int16, sx, sy int, src color.RGBA) {
+	c.put(x, y, src, c.opacity(sx, sy, src))
+}
+
+// opacity returns the opacity of the source pixel at (sx, sy) whose color is
+// src, from the color key and the mask.
+func (c *compositor) opacity(sx, sy int, src color.RGBA) float32 {
+	if c.mode == CompositeModeCopy {
+		return 1
+	}
+	if c.hasKey && src.R == c.key.R && src.G == c.key.G && src.B == c.key.B {
+		return 0
+	}
+	if c.mask == nil {
+		return 1
+	}
+	if w, h := c.mask.size(); sx >= w || sy >= h {
+		return 0
+	}
+	return float32(luminance(c.mask.at(sx, sy))) / 0xFF
+}
+
+// put draws src with the given opacity to the pixel at (x, y) of the
+// destination. The color scale is applied to src and alpha.
+func (c *compositor) put(x, y int16, src color.RGBA, alpha float32) {
+	if c.mode == CompositeModeCopy {
+		c.dst.SetPixel(x, y, c.scaleColor(src))
+		return
+	}
+
+	alpha *= c.scale.a_1 + 1
+	if alpha <= 0 {
+		return
+	}
+	src = c.scaleColor(src)
+
+	if c.mode == CompositeModeSourceOver {
+		if alpha < 1 {
+			if c.reader == nil {
+				// The destination can't be blended: draw the pixels that are
+				// more opaque than transparent.
+				if alpha < 0.5 {
+					return
+				}
+			} else {
+				src = blend(src, c.reader.PixelAt(x, y), alpha)
+			}
+		}
+		c.dst.SetPixel(x, y, src)
+		return
+	}
+
+	// The bitwise modes don't blend: the pixel is either drawn or not.
+	if alpha < 0.5 {
+		return
+	}
+	d := black
+	if c.reader != nil {
+		d = c.reader.PixelAt(x, y)
+	}
+	switch c.mode {
+	case CompositeModeOr:
+		d = color.RGBA{R: d.R | src.R, G: d.G | src.G, B: d.B | src.B, A: 0xFF}
+	case CompositeModeAnd:
+		d = color.RGBA{R: d.R & src.R, G: d.G & src.G, B: d.B & src.B, A: 0xFF}
+	case CompositeModeXor:
+		d = color.RGBA{R: d.R ^ src.R, G: d.G ^ src.G, B: d.B ^ src.B, A: 0xFF}
+	case CompositeModeInvert:
+		d = color.RGBA{R: ^d.R, G: ^d.G, B: ^d.B, A: 0xFF}
+	}
+	c.dst.SetPixel(x, y, d)
+}
+
+func (c *compositor) scaleColor(clr color.RGBA) color.RGBA {
+	if c.scale.isIdentity() {
+		return clr
+	}
+	return color.RGBA{
+		R: scaleChannel(clr.R, c.scale.r_1+1),
+		G: scaleChannel(clr.G, c.scale.g_1+1),
+		B: scaleChannel(clr.B, c.scale.b_1+1),
+		A: 0xFF,
+	}
+}
+
+func scaleChannel(v uint8, s float32) uint8 {
+	f := float32(v)*s + 0.5
+	if f <= 0 {
+		return 0
+	}
+	if f >= 0xFF {
+		return 0xFF
+	}
+	return uint8(f)
+}
+
+// blend returns src drawn over dst with the given opacity.
+func blend(src, dst color.RGBA, alpha float32) color.RGBA {
+	mix := func(s, d uint8) uint8 {
+		return uint8(float32(s)*alpha + float32(d)*(1-alpha) + 0.5)
+	}
+	return color.RGBA{R: mix(src.R, dst.R), G: mix(src.G, dst.G), B: mix(src.B, dst.B), A: 0xFF}
+}
@@ -0,0 +1,245 @@
+package koebiten
+
+import (
+	"io"
+	"strings"
+	"sync"
+
+	"tinygo.org/x/tinydraw"
+	"tinygo.org/x/tinyfont"
+)
+
+// consoleLineHeight is the height of a line of Println and of the console.
+const consoleLineHeight = 8
+
+// console is the on-screen console that keeps the output of Println, Printf
+// and ConsoleWriter.
+type console struct {
+	enabled bool
+	visible bool
+
+	// m guards lines, partial, scrollback and scroll, which are written by
+	// ConsoleWriter from any goroutine.
+	m          sync.Mutex
+	lines      []string
+	partial    string
+	scrollback int
+
+	// scroll is the number of lines the view is scrolled back from the
+	// newest line. 0 means that the view follows the output.
+	scroll int
+
+	toggleKeys []Key
+	scrollKeys bool
+	upKey      Key
+	downKey    Key
+}
+
+var theConsole = &console{
+	visible:    true,
+	scrollback: 64,
+}
+
+// SetConsoleEnabled enables or disables the on-screen console.
+//
+// While the console is enabled, Println and Printf add their output to the
+// console instead of drawing it on the screen of the frame, and the console
+// is drawn over the screen while it is visible. The console is disabled by
+// default.
+func SetConsoleEnabled(enabled bool) {
+	theConsole.enabled = enabled
+}
+
+// IsConsoleEnabled reports whether the on-screen console is enabled.
+func IsConsoleEnabled() bool {
+	return theConsole.enabled
+}
+
+// SetConsoleVisible shows or hides the on-screen console. The console is
+// visible by default.
+func SetConsoleVisible(visible bool) {
+	theConsole.visible = visible
+}
+
+// IsConsoleVisible reports whether the on-screen console is visible.
+func IsConsoleVisible() bool {
+	return theConsole.visible
+}
+
+// SetConsoleToggleKey registers a key combination that shows or hides the
+// console. The console is toggled when all the keys are pressed and one of
+// them has just been pressed.
+//
+// Calling SetConsoleToggleKey with no keys removes the key combination.
+func SetConsoleToggleKey(keys ...Key) {
+	theConsole.toggleKeys = append(theConsole.toggleKeys[:0], keys...)
+}
+
+// SetConsoleScrollKeys registers the keys that scroll the console back and
+// forward while it is visible.
+func SetConsoleScrollKeys(up, down Key) {
+	theConsole.scrollKeys = true
+	theConsole.upKey = up
+	theConsole.downKey = down
+}
+
+// SetConsoleScrollback sets the number of lines kept by the console.
+// The default value is 64.
+func SetConsoleScrollback(lines int) {
+	theConsole.m.Lock()
+	defer theConsole.m.Unlock()
+	theConsole.scrollback = max(lines, 1)
+	theConsole.trim()
+}
+
+// ScrollConsole scrolls the console back by n lines, or forward if n is
+// negative. While the console is scrolled back, the view stays on the same
+// lines; it follows the output again once it is scrolled to the newest line.
+func ScrollConsole(n int) {
+	theConsole.m.Lock()
+	defer theConsole.m.Unlock()
+	theConsole.scroll = min(max(theConsole.scroll+n, 0), max(len(theConsole.lines)-1, 0))
+}
+
+// ClearConsole removes all the lines of the console.
+func ClearConsole() {
+	theConsole.m.Lock()
+	defer theConsole.m.Unlock()
+	theConsole.lines = theConsole.lines[:0]
+	theConsole.partial = ""
+	theConsole.scroll = 0
+}
+
+// ConsoleWriter returns an io.Writer that writes to the on-screen console,
+// for example to show the output of the log package:
+//
+//	log.SetOutput(koebiten.ConsoleWriter())
+//
+// Lines are ended by '\n'. The output is kept even if the console is not
+// enabled. The writer can be used by multiple goroutines.
+func ConsoleWriter() io.Writer {
+	return theConsole
+}
+
+// Write adds p to the console. It implements io.Writer.
+func (c *console) Write(p []byte) (int, error) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	s := c.partial + string(p)
+	for {
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			break
+		}
+		c.addLine(s[:i])
+		s = s[i+1:]
+	}
+	c.partial = s
+	return len(p), nil
+}
+
+// addLine adds a line to the console. c.m must be held.
+func (c *console) addLine(line string) {
+	c.lines = append(c.lines, strings.TrimSuffix(line, "\r"))
+	if c.scroll > 0 {
+		// Keep the view on the same lines.
+		c.scroll++
+	}
+	c.trim()
+}
+
+// trim removes the oldest lines that don't fit in the scrollback. c.m must
+// be held.
+func (c *console) trim() {
+	if n := len(c.lines) - c.scrollback; n > 0 {
+		c.lines = append(c.lines[:0], c.lines[n:]...)
+	}
+	c.scroll = min(c.scroll, max(len(c.lines)-1, 0))
+}
+
+// update handles the keys of the console. It is called by the main loop
+// after the input is updated.
+func (c *console) update() {
+	if !c.enabled {
+		return
+	}
+	if len(c.toggleKeys) > 0 {
+		just := false
+		pressed := true
+		for _, k := range c.toggleKeys {
+			if !IsKeyPressed(k) {
+				pressed = false
+				break
+			}
+			if IsKeyJustPressed(k) {
+				just = true
+			}
+		}
+		if pressed && just {
+			c.visible = !c.visible
+		}
+	}
+	if c.visible && c.scrollKeys {
+		if IsKeyJustPressed(c.upKey) {
+			ScrollConsole(1)
+		}
+		if IsKeyJustPressed(c.downKey) {
+			ScrollConsole(-1)
+		}
+	}
+}
+
+// draw draws the console over the bottom of the screen if it is enabled and
+// visible. Only the rows of the shown lines are cleared, so the rest of the
+// frame stays visible.
+func (c *console) draw(dst Displayer) {
+	if !c.enabled || !c.visible || isNil(dst) {
+		return
+	}
+	c.m.Lock()
+	defer c.m.Unlock()
+	lines := c.lines
+	if c.partial != "" {
+		lines = append(lines[:len(lines):len(lines)], c.partial)
+	}
+	end := len(lines) - c.scroll
+	w, h := dst.Size()
+	n := int(h) / consoleLineHeight
+	start := max(end-n, 0)
+	if start >= end {
+		return
+	}
+
+	top := h - int16((end-start)*consoleLineHeight)
+	tinydraw.FilledRectangle(dst, 0, top, w, h-top, black)
+	for i, line := range lines[start:end] {
+		tinyfont.WriteLine(dst, &tinyfont.Org01, 2, top+int16((i+1)*consoleLineHeight), line, white)
+	}
+	if c.scroll > 0 {
+		// Mark that the view is scrolled back.
+		tinydraw.FilledRectangle(dst, w-2, h-consoleLineHeight, 2, consoleLineHeight, white)
+	}
+}
+
+// printText prints the lines of s to the console, or to the screen of the
+// frame if the console is not enabled. The text of a call always ends its
+// line.
+func printText(s string) {
+	s = strings.TrimSuffix(s, "\n")
+	if theConsole.enabled {
+		theConsole.Write([]byte(s + "\n"))
+		return
+	}
+	if isNil(display) {
+		return
+	}
+	_, h := display.Size()
+	for _, line := range strings.Split(s, "\n") {
+		if textY >= h {
+			// The lines below the screen are not drawn.
+			return
+		}
+		textY += consoleLineHeight
+		tinyfont.WriteLine(display, &tinyfont.Org01, 2, textY, line, white)
+	}
+}
@@ -0,0 +1,161 @@
+package koebiten
+
+import (
+	"image"
+)
+
+const (
+	dirtyTileWidth  = 16
+	dirtyTileHeight = 16
+)
+
+// DirtyTracker finds the regions of a framebuffer that changed since the
+// previous frame, so that a Displayer can send only those regions to a
+// display that supports partial updates.
+//
+// The framebuffer is divided into tiles and a checksum of every tile is kept
+// instead of a copy of the previous frame. A tile whose checksum is unchanged
+// is considered clean.
+type DirtyTracker struct {
+	width  int
+	height int
+	tileW  int
+	tileH  int
+	bpp    int
+	paged  bool
+	cols   int
+	rows   int
+	sums   []uint32
+	valid  bool
+	spans  []image.Rectangle
+	merged []image.Rectangle
+}
+
+// NewDirtyTracker creates a DirtyTracker for a row-major framebuffer of the
+// specified size with bitsPerPixel bits per pixel, such as pixel.Image.
+func NewDirtyTracker(width, height, bitsPerPixel int) *DirtyTracker {
+	return newDirtyTracker(width, height, dirtyTileWidth, dirtyTileHeight, bitsPerPixel, false)
+}
+
+// NewPagedDirtyTracker creates a DirtyTracker for the framebuffer of a
+// page-based monochrome display such as SSD1306 or SH1106. Each byte holds a
+// column of 8 vertical pixels, and byte x+page*width is column x of the page.
+//
+// The regions returned by Update are aligned to pages.
+func NewPagedDirtyTracker(width, height int) *DirtyTracker {
+	return newDirtyTracker(width, height, dirtyTileWidth, 8, 1, true)
+}
+
+func newDirtyTracker(width, height, tileW, tileH, bpp int, paged bool) *DirtyTracker {
+	cols := (width + tileW - 1) / tileW
+	rows := (height + tileH - 1) / tileH
+	return &DirtyTracker{
+		width:  width,
+		height: height,
+		tileW:  tileW,
+		tileH:  tileH,
+		bpp:    bpp,
+		paged:  paged,
+		cols:   cols,
+		rows:   rows,
+		sums:   make([]uint32, cols*rows),
+	}
+}
+
+// Invalidate marks the whole framebuffer as dirty, for example after the
+// display was cleared or reinitialized.
+func (t *DirtyTracker) Invalidate() {
+	t.valid = false
+}
+
+// Update compares buf with the framebuffer passed to the previous call and
+// appends the changed regions to rects[:0]. Regions are in pixels and don't
+// overlap. The first call, and the first call after Invalidate, returns the
+// whole framebuffer.
+func (t *DirtyTracker) Update(buf []byte, rects []image.Rectangle) []image.Rectangle {
+	rects = rects[:0]
+	t.merged = t.merged[:0]
+	for ty := 0; ty < t.rows; ty++ {
+		t.spans = t.spans[:0]
+		start := -1
+		for tx := 0; tx <= t.cols; tx++ {
+			dirty := false
+			if tx < t.cols {
+				i := ty*t.cols + tx
+				sum := t.checksum(buf, tx, ty)
+				dirty = !t.valid || sum != t.sums[i]
+				t.sums[i] = sum
+			}
+			if dirty && start < 0 {
+				start = tx
+			} else if !dirty && start >= 0 {
+				t.spans = append(t.spans, t.tileRect(start, tx, ty))
+				start = -1
+			}
+		}
+
+		// Extend the regions of the previous tile row that have the same
+		// horizontal span, and finish the others.
+		n := 0
+		for _, r := range t.merged {
+			extended := false
+			for i, s := range t.spans {
+				if s.Min.X == r.Min.X && s.Max.X == r.Max.X && s.Min.Y == r.Max.Y {
+					r.Max.Y = s.Max.Y
+					t.spans[i] = image.Rectangle{}
+					extended = true
+					break
+				}
+			}
+			if extended {
+				t.merged[n] = r
+				n++
+			} else {
+				rects = append(rects, r)
+			}
+		}
+		t.merged = t.merged[:n]
+		for _, s := range t.spans {
+			if !s.Empty() {
+				t.merged = append(t.merged, s)
+			}
+		}
+	}
+	rects = append(rects, t.merged...)
+	t.valid = true
+	return rects
+}
+
+// tileRect returns the rectangle of the tiles from x0 to x1 (exclusive) in
+// the tile row y.
+func (t *DirtyTracker) tileRect(x0, x1, y int) image.Rectangle {
+	r := image.Rect(x0*t.tileW, y*t.tileH, x1*t.tileW, (y+1)*t.tileH)
+	return r.Intersect(image.Rect(0, 0, t.width, t.height))
+}
+
+// checksum returns the FNV-1a hash of the bytes of a tile.
+func (t *DirtyTracker) checksum(buf []byte, tx, ty int) uint32 {
+	h := uint32(2166136261)
+	r := t.tileRect(tx, tx+1, ty)
+	if t.paged {
+		return fnv1a(h, buf, ty*t.width+r.Min.X, ty*t.width+r.Max.X)
+	}
+	stride := (t.width*t.bpp + 7) / 8
+	x0 := r.Min.X * t.bpp / 8
+	x1 := (r.Max.X*t.bpp + 7) / 8
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		h = fnv1a(h, buf, y*stride+x0, y*stride+x1)
+	}
+	return h
+}
+
+func fnv1a(h uint32, buf []byte, start, end int) uint32 {
+	if end > len(buf) {
+		end = len(buf)
+	}
+	for i := start; i < end; i++ {
+		h ^= uint32(buf[i])
+		h *= 16777619
+	}
+	return h
+}
@@ -0,0 +1,57 @@
+package koebiten_test
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/sago35/koebiten"
+)
+
+func TestDirtyTracker(t *testing.T) {
+	const w, h = 64, 48
+	buf := make([]byte, w*h*2)
+	tr := koebiten.NewDirtyTracker(w, h, 16)
+
+	rects := tr.Update(buf, nil)
+	if want := []image.Rectangle{image.Rect(0, 0, w, h)}; !reflect.DeepEqual(rects, want) {
+		t.Fatalf("first Update: got %v, want %v", rects, want)
+	}
+	if rects = tr.Update(buf, rects); len(rects) != 0 {
+		t.Fatalf("unchanged Update: got %v, want none", rects)
+	}
+
+	set := func(x, y int) { buf[(y*w+x)*2] ^= 0xFF }
+	set(1, 1)
+	set(20, 17)
+	set(20, 40)
+	set(50, 40)
+	rects = tr.Update(buf, rects)
+	want := []image.Rectangle{
+		image.Rect(0, 0, 16, 16),
+		image.Rect(16, 16, 32, 48),
+		image.Rect(48, 32, 64, 48),
+	}
+	if !reflect.DeepEqual(rects, want) {
+		t.Errorf("Update: got %v, want %v", rects, want)
+	}
+
+	tr.Invalidate()
+	if rects = tr.Update(buf, rects); len(rects) != 1 || rects[0] != image.Rect(0, 0, w, h) {
+		t.Errorf("Update after Invalidate: got %v", rects)
+	}
+}
+
+func TestPagedDirtyTracker(t *testing.T) {
+	const w, h = 128, 64
+	buf := make([]byte, w*h/8)
+	tr := koebiten.NewPagedDirtyTracker(w, h)
+	tr.Update(buf, nil)
+
+	// Pixel (40, 13) is bit 5 of byte 40 in page 1.
+	buf[40+1*w] |= 1 << 5
+	rects := tr.Update(buf, nil)
+	if want := []image.Rectangle{image.Rect(32, 8, 48, 16)}; !reflect.DeepEqual(rects, want) {
+		t.Errorf("Update: got %v, want %v", rects, want)
+	}
+}
@@ -0,0 +1,165 @@
+package koebiten
+
+import (
+	"image/color"
+)
+
+// Dither represents how colors are reduced to the levels of a monochrome or
+// grayscale pixel format when an image is converted.
+type Dither int
+
+const (
+	// DitherNone rounds each pixel to the nearest level. For a monochrome
+	// image, the pixels whose luminance is at least the threshold are set.
+	DitherNone Dither = iota
+
+	// DitherBayer2x2 applies ordered dithering with a 2x2 Bayer matrix.
+	DitherBayer2x2
+
+	// DitherBayer4x4 applies ordered dithering with a 4x4 Bayer matrix.
+	DitherBayer4x4
+
+	// DitherBayer8x8 applies ordered dithering with an 8x8 Bayer matrix.
+	DitherBayer8x8
+
+	// DitherFloydSteinberg applies Floyd–Steinberg error-diffusion dithering.
+	// It gives the smoothest gradients, but needs a pass over the whole image.
+	DitherFloydSteinberg
+)
+
+// bayerMatrix is an n x n ordered dither matrix with values from 0 to n*n-1.
+type bayerMatrix struct {
+	n int
+	m []uint8
+}
+
+// newBayerMatrix builds the Bayer matrix of size n, which is a power of 2,
+// by recursively expanding the 2x2 matrix.
+func newBayerMatrix(n int) bayerMatrix {
+	m := []uint8{0}
+	for size := 1; size < n; size *= 2 {
+		next := make([]uint8, 4*size*size)
+		for y := 0; y < size; y++ {
+			for x := 0; x < size; x++ {
+				v := 4 * m[y*size+x]
+				next[y*2*size+x] = v
+				next[y*2*size+x+size] = v + 2
+				next[(y+size)*2*size+x] = v + 3
+				next[(y+size)*2*size+x+size] = v + 1
+			}
+		}
+		m = next
+	}
+	return bayerMatrix{n: n, m: m}
+}
+
+func (b bayerMatrix) at(x, y int) uint8 {
+	return b.m[(y%b.n)*b.n+x%b.n]
+}
+
+var (
+	bayer2x2 = newBayerMatrix(2)
+	bayer4x4 = newBayerMatrix(4)
+	bayer8x8 = newBayerMatrix(8)
+)
+
+// levels returns the number of levels of gray of a format, or 0 if the format
+// stores colors.
+func (f PixelFormat) levels() int {
+	switch f {
+	case FormatMonochrome:
+		return 2
+	case FormatGray2:
+		return 4
+	case FormatGray4:
+		return 16
+	}
+	return 0
+}
+
+// quantizeLuma converts the luminance of every pixel in lum, which has width
+// w, to the levels of the format of dst with the dithering of options.
+func quantizeLuma(dst *Image, lum []uint8, w int, options *NewImageFromImageOptions) {
+	if w == 0 {
+		return
+	}
+	h := len(lum) / w
+	top := options.Format.levels() - 1
+	step := 255 / float32(top)
+	gray := func(level int) color.RGBA {
+		v := uint8(level * 255 / top)
+		return color.RGBA{R: v, G: v, B: v, A: 0xFF}
+	}
+
+	threshold := 0x80
+	if options.Threshold != nil {
+		threshold = int(*options.Threshold)
+	}
+	// The dark pixels of a monochrome image are set, so its luminance is
+	// inverted unless Invert is set.
+	if options.Invert != (top == 1) {
+		for i := range lum {
+			lum[i] = 0xFF - lum[i]
+		}
+		// l < threshold is 0xFF-l >= 0x100-threshold.
+		threshold = 0x100 - threshold
+	}
+
+	var matrix bayerMatrix
+	switch options.Dither {
+	case DitherBayer2x2:
+		matrix = bayer2x2
+	case DitherBayer4x4:
+		matrix = bayer4x4
+	case DitherBayer8x8:
+		matrix = bayer8x8
+	case DitherFloydSteinberg:
+		floydSteinberg(dst, lum, w, h, top, gray)
+		return
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			l := int(lum[y*w+x])
+			var level int
+			switch {
+			case matrix.n > 0:
+				// Shift the value by an offset in (-0.5, 0.5) of a level,
+				// then round it.
+				nn := float32(matrix.n * matrix.n)
+				v := float32(l)/step + (float32(matrix.at(x, y))+0.5)/nn - 0.5
+				level = int(v + 0.5)
+			case top == 1:
+				if l >= threshold {
+					level = 1
+				}
+			default:
+				level = (l*top + 127) / 255
+			}
+			dst.set(x, y, gray(min(max(level, 0), top)))
+		}
+	}
+}
+
+// floydSteinberg quantizes lum with Floyd–Steinberg error diffusion. The
+// error of the current and the next rows is kept, so it only needs two rows
+// of memory.
+func floydSteinberg(dst *Image, lum []uint8, w, h, top int, gray func(int) color.RGBA) {
+	cur := make([]int16, w+2)
+	next := make([]int16, w+2)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := int(lum[y*w+x]) + int(cur[x+1])/16
+			level := min(max((v*top+127)/255, 0), top)
+			dst.set(x, y, gray(level))
+
+			e := int16(v - level*255/top)
+			cur[x+2] += e * 7
+			next[x] += e * 3
+			next[x+1] += e * 5
+			next[x+2] += e
+		}
+		cur, next = next, cur
+		clear(next)
+	}
+}
@@ -0,0 +1,62 @@
+package koebiten_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/sago35/koebiten"
+)
+
+func threshold(v uint8) *uint8 {
+	return &v
+}
+
+func TestNewImageFromImageDither(t *testing.T) {
+	// A 16x16 image of 25% gray.
+	src := image.NewGray(image.Rect(0, 0, 16, 16))
+	for i := range src.Pix {
+		src.Pix[i] = 0x40
+	}
+	countSet := func(img *koebiten.Image) int {
+		n := 0
+		b := img.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if r, _, _, _ := img.At(x, y).RGBA(); r != 0 {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	tests := []struct {
+		name    string
+		options koebiten.NewImageFromImageOptions
+		min     int
+		max     int
+	}{
+		{"threshold", koebiten.NewImageFromImageOptions{}, 256, 256},
+		{"low threshold", koebiten.NewImageFromImageOptions{Threshold: threshold(0x40)}, 0, 0},
+		{"zero threshold", koebiten.NewImageFromImageOptions{Threshold: threshold(0), Invert: true}, 256, 256},
+		{"invert", koebiten.NewImageFromImageOptions{Invert: true}, 0, 0},
+		{"bayer 2x2", koebiten.NewImageFromImageOptions{Dither: koebiten.DitherBayer2x2}, 192, 192},
+		{"bayer 4x4", koebiten.NewImageFromImageOptions{Dither: koebiten.DitherBayer4x4}, 192, 192},
+		{"bayer 8x8", koebiten.NewImageFromImageOptions{Dither: koebiten.DitherBayer8x8}, 192, 192},
+		{"floyd-steinberg", koebiten.NewImageFromImageOptions{Dither: koebiten.DitherFloydSteinberg}, 184, 200},
+		{"bayer 4x4 inverted", koebiten.NewImageFromImageOptions{Dither: koebiten.DitherBayer4x4, Invert: true}, 64, 64},
+	}
+	for _, tt := range tests {
+		img := koebiten.NewImageFromImage(src, &tt.options)
+		if got := countSet(img); got < tt.min || tt.max < got {
+			t.Errorf("%s: %d pixels set, want %d to %d", tt.name, got, tt.min, tt.max)
+		}
+	}
+
+	// Grayscale formats keep the levels they can store.
+	img := koebiten.NewImageFromImage(src, &koebiten.NewImageFromImageOptions{Format: koebiten.FormatGray4})
+	if got := img.At(3, 3); got != (color.RGBA{R: 0x44, G: 0x44, B: 0x44, A: 0xFF}) {
+		t.Errorf("Gray4 pixel = %v", got)
+	}
+}
@@ -12,6 +12,7 @@ func main() {
 	koebiten.SetHardware(hardware.Device)
 	koebiten.SetWindowSize(128, 64)
 	koebiten.SetWindowTitle("Drawing")
+	koebiten.SetTPS(30)
 
 	game := drawing.NewGame()
 
@@ -0,0 +1,121 @@
+package koebiten
+
+import (
+	"image/color"
+
+	"github.com/chewxy/math32"
+)
+
+// Filter represents the type of texture filter used by DrawImage when the
+// image is scaled or rotated.
+type Filter int
+
+const (
+	// FilterNearest represents nearest (crisp-edged) filter.
+	// Each pixel of the destination takes the color of the source pixel under
+	// its center.
+	FilterNearest Filter = iota
+
+	// FilterArea averages several samples of the source under each pixel of
+	// the destination. Edges and scaled-down images are smoothed, and the
+	// partially covered pixels are blended with the destination.
+	FilterArea
+
+	// FilterThreshold samples like FilterArea, but draws a pixel only if at
+	// least half of it is covered by the image, without blending. It keeps
+	// scaled-down images readable on monochrome displays.
+	FilterThreshold
+)
+
+// maxFilterSamples is the maximum number of samples per axis taken by
+// FilterArea and FilterThreshold for a pixel of the destination.
+const maxFilterSamples = 4
+
+// drawTransformed draws src to the destination of c with the geometry matrix
+// geoM. It iterates the pixels of the destination covered by the image and
+// maps them back to the source through the inverted matrix, so the image has
+// no holes when it is scaled up or rotated.
+func drawTransformed(c *compositor, src *Image, geoM GeoM, filter Filter) {
+	w, h := src.size()
+	if w == 0 || h == 0 {
+		return
+	}
+
+	// The bounding box of the image on the destination, clipped to it.
+	minX, minY := math32.Inf(1), math32.Inf(1)
+	maxX, maxY := math32.Inf(-1), math32.Inf(-1)
+	for _, p := range [4][2]float32{{0, 0}, {float32(w), 0}, {0, float32(h)}, {float32(w), float32(h)}} {
+		x, y := geoM.Apply(p[0], p[1])
+		minX, maxX = math32.Min(minX, x), math32.Max(maxX, x)
+		minY, maxY = math32.Min(minY, y), math32.Max(maxY, y)
+	}
+	dw, dh := c.dst.Size()
+	x0 := max(int(math32.Floor(minX)), 0)
+	y0 := max(int(math32.Floor(minY)), 0)
+	x1 := min(int(math32.Ceil(maxX)), int(dw))
+	y1 := min(int(math32.Ceil(maxY)), int(dh))
+
+	inv := geoM
+	inv.Invert()
+	a, b, cc, d, tx, ty := inv.elements32()
+
+	n := 1
+	if filter != FilterNearest {
+		// Take enough samples to cover every source pixel under a destination
+		// pixel, and at least 2 to smooth the edges.
+		scale := math32.Max(math32.Hypot(a, cc), math32.Hypot(b, d))
+		n = min(max(int(math32.Ceil(scale)), 2), maxFilterSamples)
+	}
+	step := 1 / float32(n)
+
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			if n == 1 {
+				// Sample at the center of the pixel.
+				fx, fy := float32(x)+0.5, float32(y)+0.5
+				u := int(math32.Floor(a*fx + b*fy + tx))
+				v := int(math32.Floor(cc*fx + d*fy + ty))
+				if 0 <= u && u < w && 0 <= v && v < h {
+					c.draw(int16(x), int16(y), u, v, src.at(u, v))
+				}
+				continue
+			}
+
+			var r, g, bl, alpha float32
+			for j := 0; j < n; j++ {
+				fy := float32(y) + (float32(j)+0.5)*step
+				for i := 0; i < n; i++ {
+					fx := float32(x) + (float32(i)+0.5)*step
+					u := int(math32.Floor(a*fx + b*fy + tx))
+					v := int(math32.Floor(cc*fx + d*fy + ty))
+					if u < 0 || w <= u || v < 0 || h <= v {
+						continue
+					}
+					s := src.at(u, v)
+					o := c.opacity(u, v, s)
+					r += float32(s.R) * o
+					g += float32(s.G) * o
+					bl += float32(s.B) * o
+					alpha += o
+				}
+			}
+			if alpha == 0 {
+				continue
+			}
+			clr := color.RGBA{
+				R: uint8(r/alpha + 0.5),
+				G: uint8(g/alpha + 0.5),
+				B: uint8(bl/alpha + 0.5),
+				A: 0xFF,
+			}
+			alpha /= float32(n * n)
+			if filter == FilterThreshold {
+				if alpha < 0.5 {
+					continue
+				}
+				alpha = 1
+			}
+			c.put(int16(x), int16(y), clr, alpha)
+		}
+	}
+}
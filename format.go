@@ -0,0 +1,240 @@
+package koebiten
+
+import (
+	"image/color"
+
+	"tinygo.org/x/drivers/pixel"
+)
+
+// PixelFormat is the format of the pixels stored in an Image.
+type PixelFormat int
+
+const (
+	// FormatMonochrome stores 1 bit per pixel. It is the format of NewImage.
+	FormatMonochrome PixelFormat = iota
+
+	// FormatGray2 stores 2 bits per pixel, which are 4 levels of gray.
+	FormatGray2
+
+	// FormatGray4 stores 4 bits per pixel, which are 16 levels of gray.
+	FormatGray4
+
+	// FormatRGB565 stores 16 bits per pixel in the big-endian RGB565 format
+	// of the TFT displays.
+	FormatRGB565
+)
+
+// String returns the name of the format.
+func (f PixelFormat) String() string {
+	switch f {
+	case FormatMonochrome:
+		return "Monochrome"
+	case FormatGray2:
+		return "Gray2"
+	case FormatGray4:
+		return "Gray4"
+	case FormatRGB565:
+		return "RGB565"
+	}
+	return "PixelFormat(?)"
+}
+
+// BitsPerPixel returns the number of bits used to store a pixel.
+func (f PixelFormat) BitsPerPixel() int {
+	switch f {
+	case FormatMonochrome:
+		return 1
+	case FormatGray2:
+		return 2
+	case FormatGray4:
+		return 4
+	case FormatRGB565:
+		return 16
+	}
+	return 0
+}
+
+// Model returns the color model of the format. It converts a color to the
+// nearest color that the format can store.
+func (f PixelFormat) Model() color.Model {
+	switch f {
+	case FormatMonochrome:
+		return monochromeModel
+	case FormatGray2:
+		return gray2Model
+	case FormatGray4:
+		return gray4Model
+	case FormatRGB565:
+		return rgb565Model
+	}
+	return color.RGBAModel
+}
+
+var (
+	monochromeModel = color.ModelFunc(func(c color.Color) color.Color {
+		r := color.RGBAModel.Convert(c).(color.RGBA)
+		return pixel.NewMonochrome(r.R, r.G, r.B).RGBA()
+	})
+	gray2Model = color.ModelFunc(func(c color.Color) color.Color {
+		return grayModel(2, c)
+	})
+	gray4Model = color.ModelFunc(func(c color.Color) color.Color {
+		return grayModel(4, c)
+	})
+	rgb565Model = color.ModelFunc(func(c color.Color) color.Color {
+		r := color.RGBAModel.Convert(c).(color.RGBA)
+		return pixel.NewRGB565BE(r.R, r.G, r.B).RGBA()
+	})
+)
+
+func grayModel(bits int, c color.Color) color.RGBA {
+	l := grayLevel(bits, color.RGBAModel.Convert(c).(color.RGBA))
+	v := uint8(uint(l) * 255 / uint(1<<bits-1))
+	return color.RGBA{R: v, G: v, B: v, A: 0xFF}
+}
+
+// pixelBuffer stores the pixels of an Image in one of the pixel formats.
+// Colors are converted to and from color.RGBA.
+type pixelBuffer interface {
+	size() (int, int)
+	rgbaAt(x, y int) color.RGBA
+	setRGBA(x, y int, c color.RGBA)
+	fill(c color.RGBA)
+}
+
+func newPixelBuffer(format PixelFormat, width, height int) pixelBuffer {
+	switch format {
+	case FormatMonochrome:
+		return pixelImage[pixel.Monochrome]{img: pixel.NewImage[pixel.Monochrome](width, height)}
+	case FormatGray2:
+		return newGrayImage(2, width, height)
+	case FormatGray4:
+		return newGrayImage(4, width, height)
+	case FormatRGB565:
+		return pixelImage[pixel.RGB565BE]{img: pixel.NewImage[pixel.RGB565BE](width, height)}
+	}
+	panic("koebiten: unknown pixel format")
+}
+
+// pixelImage is a pixelBuffer for the formats supported by pixel.Image.
+type pixelImage[T pixel.Color] struct {
+	img pixel.Image[T]
+}
+
+func (p pixelImage[T]) size() (int, int) {
+	return p.img.Size()
+}
+
+func (p pixelImage[T]) rgbaAt(x, y int) color.RGBA {
+	return p.img.Get(x, y).RGBA()
+}
+
+func (p pixelImage[T]) setRGBA(x, y int, c color.RGBA) {
+	p.img.Set(x, y, pixel.NewColor[T](c.R, c.G, c.B))
+}
+
+func (p pixelImage[T]) fill(c color.RGBA) {
+	clr := pixel.NewColor[T](c.R, c.G, c.B)
+	w, h := p.img.Size()
+	n := w * h
+	if n == 0 {
+		return
+	}
+	buf := p.img.RawBuffer()
+	var zero T
+	if bits := zero.BitsPerPixel(); bits%8 == 0 {
+		// Copy the bytes of the first pixel over the whole buffer.
+		p.img.Set(0, 0, clr)
+		for i := bits / 8; i < len(buf); i *= 2 {
+			copy(buf[i:], buf[:i])
+		}
+		return
+	}
+
+	// Monochrome: fill the whole bytes, then the pixels of the last byte.
+	var b byte
+	if clr != zero {
+		b = 0xFF
+	}
+	for i := 0; i < n/8; i++ {
+		buf[i] = b
+	}
+	for i := n / 8 * 8; i < n; i++ {
+		p.img.Set(i%w, i/w, clr)
+	}
+}
+
+// grayImage is a pixelBuffer for grayscale formats of 2 or 4 bits per pixel.
+// Pixels are packed from the most significant bit and each row starts at a
+// byte boundary.
+type grayImage struct {
+	bits   int
+	width  int
+	height int
+	stride int
+	pix    []byte
+}
+
+func newGrayImage(bits, width, height int) *grayImage {
+	stride := (width*bits + 7) / 8
+	return &grayImage{
+		bits:   bits,
+		width:  width,
+		height: height,
+		stride: stride,
+		pix:    make([]byte, stride*height),
+	}
+}
+
+func (g *grayImage) size() (int, int) {
+	return g.width, g.height
+}
+
+func (g *grayImage) rgbaAt(x, y int) color.RGBA {
+	i, shift := g.offset(x, y)
+	top := uint8(1<<g.bits - 1)
+	v := (g.pix[i] >> shift) & top
+	l := uint8(uint(v) * 255 / uint(top))
+	return color.RGBA{R: l, G: l, B: l, A: 0xFF}
+}
+
+func (g *grayImage) setRGBA(x, y int, c color.RGBA) {
+	i, shift := g.offset(x, y)
+	top := uint8(1<<g.bits - 1)
+	g.pix[i] = g.pix[i]&^(top<<shift) | g.level(c)<<shift
+}
+
+func (g *grayImage) fill(c color.RGBA) {
+	v := g.level(c)
+	b := byte(0)
+	for i := 0; i < 8; i += g.bits {
+		b = b<<g.bits | v
+	}
+	for i := range g.pix {
+		g.pix[i] = b
+	}
+}
+
+// offset returns the index of the byte of the pixel at (x, y) and the shift
+// of its bits in the byte.
+func (g *grayImage) offset(x, y int) (int, uint) {
+	bit := x * g.bits
+	return y*g.stride + bit/8, uint(8 - g.bits - bit%8)
+}
+
+// level returns the gray level of c, rounded to the nearest level.
+func (g *grayImage) level(c color.RGBA) uint8 {
+	return grayLevel(g.bits, c)
+}
+
+// grayLevel returns the level of c in a grayscale of the given bits, rounded
+// to the nearest level.
+func grayLevel(bits int, c color.RGBA) uint8 {
+	top := uint(1<<bits - 1)
+	return uint8((uint(luminance(c))*top + 127) / 255)
+}
+
+// luminance returns the luma of c as defined by ITU-R BT.601.
+func luminance(c color.RGBA) uint8 {
+	return uint8((299*uint(c.R) + 587*uint(c.G) + 114*uint(c.B)) / 1000)
+}
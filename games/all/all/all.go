@@ -1,8 +1,6 @@
 package all
 
 import (
-	"log"
-
 	"github.com/sago35/koebiten"
 	"github.com/sago35/koebiten/games/blocks/blocks"
 	"github.com/sago35/koebiten/games/flappygopher/flappygopher"
@@ -13,7 +11,7 @@ import (
 
 type Game struct {
 	Title string
-	Game  func()
+	Game  func() error
 }
 
 type Menu struct {
@@ -29,52 +27,37 @@ func NewGame() *Menu {
 	menu.AddGames([]Game{
 		{
 			Title: "Flappy Gopher",
-			Game: func() {
+			Game: func() error {
 				koebiten.SetRotation(koebiten.Rotation0)
-				game := flappygopher.NewGame()
-				if err := koebiten.RunGame(game); err != nil {
-					log.Fatal(err)
-				}
+				return koebiten.RunGame(flappygopher.NewGame())
 			},
 		},
 		{
 			Title: "Blocks",
-			Game: func() {
+			Game: func() error {
 				koebiten.SetRotation(koebiten.Rotation90)
-				game := blocks.NewGame()
-				if err := koebiten.RunGame(game); err != nil {
-					log.Fatal(err)
-				}
+				return koebiten.RunGame(blocks.NewGame())
 			},
 		},
 		{
 			Title: "Jumpin Gopher",
-			Game: func() {
+			Game: func() error {
 				koebiten.SetRotation(koebiten.Rotation0)
-				game := jumpingopher.NewGame()
-				if err := koebiten.RunGame(game); err != nil {
-					log.Fatal(err)
-				}
+				return koebiten.RunGame(jumpingopher.NewGame())
 			},
 		},
 		{
 			Title: "Snake Game",
-			Game: func() {
+			Game: func() error {
 				koebiten.SetRotation(koebiten.Rotation0)
-				game := snakegame.NewGame()
-				if err := koebiten.RunGame(game); err != nil {
-					log.Fatal(err)
-				}
+				return koebiten.RunGame(snakegame.NewGame())
 			},
 		},
 		{
 			Title: "Goradius",
-			Game: func() {
+			Game: func() error {
 				koebiten.SetRotation(koebiten.Rotation0)
-				game := goradius.NewGame()
-				if err := koebiten.RunGame(game); err != nil {
-					log.Fatal(err)
-				}
+				return koebiten.RunGame(goradius.NewGame())
 			},
 		},
 	})
@@ -107,6 +90,7 @@ func (m *Menu) AddGames(game []Game) {
 	m.games = append(m.games, game...)
 }
 
-func (m *Menu) RunCurrentGame() {
-	m.games[m.index].Game()
+// RunCurrentGame runs the selected game until it ends.
+func (m *Menu) RunCurrentGame() error {
+	return m.games[m.index].Game()
 }
@@ -12,15 +12,18 @@ func main() {
 	koebiten.SetHardware(hardware.Device)
 	koebiten.SetWindowSize(64, 128)
 	koebiten.SetWindowTitle("All")
+	koebiten.SetTPS(30)
 
 	game := all.NewGame()
 
 	for {
-		koebiten.SetRotation(0)
+		koebiten.SetRotation(koebiten.Rotation0)
 		if err := koebiten.RunGame(game); err != nil {
 			log.Fatal(err)
 		}
 
-		game.RunCurrentGame()
+		if err := game.RunCurrentGame(); err != nil {
+			log.Fatal(err)
+		}
 	}
 }
@@ -13,6 +13,7 @@ func main() {
 	koebiten.SetRotation(koebiten.Rotation90)
 	koebiten.SetWindowSize(64, 128)
 	koebiten.SetWindowTitle("Tetris in Go")
+	koebiten.SetTPS(30)
 
 	game := blocks.NewGame()
 
@@ -12,6 +12,7 @@ func main() {
 	koebiten.SetHardware(hardware.Device)
 	koebiten.SetWindowSize(128, 64)
 	koebiten.SetWindowTitle("Flappy Gopher")
+	koebiten.SetTPS(30)
 
 	game := flappygopher.NewGame()
 
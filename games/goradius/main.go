@@ -13,6 +13,7 @@ func main() {
 	koebiten.SetHardware(hardware.Device)
 	koebiten.SetWindowSize(128, 64)
 	koebiten.SetWindowTitle("GeoM Gopher")
+	koebiten.SetTPS(30)
 
 	game := goradius.NewGame()
 
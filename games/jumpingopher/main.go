@@ -12,6 +12,7 @@ func main() {
 	koebiten.SetHardware(hardware.Device)
 	koebiten.SetWindowSize(128, 64)
 	koebiten.SetWindowTitle("Jumpin Gopher")
+	koebiten.SetTPS(30)
 	game := jumpingopher.NewGame()
 
 	if err := koebiten.RunGame(game); err != nil {
@@ -25,6 +25,7 @@ func main() {
 	koebiten.SetHardware(hardware.Device)
 	koebiten.SetWindowSize(128, 64)
 	koebiten.SetWindowTitle("Snake Game")
+	koebiten.SetTPS(30)
 
 	game := snakegame.NewGame()
 	koebiten.RunGame(game)
@@ -21,7 +21,7 @@ func (z CONF2025BADGE) Init() error {
 }
 
 func (z CONF2025BADGE) GetDisplay() koebiten.Displayer {
-	return Display
+	return display
 }
 
 func (z CONF2025BADGE) KeyUpdate() error {
@@ -30,6 +30,7 @@ func (z CONF2025BADGE) KeyUpdate() error {
 
 var (
 	Display *ssd1306.Device
+	display *ssd1306Display
 )
 
 var (
@@ -99,6 +100,7 @@ func Init() error {
 	d.SetRotation(drivers.Rotation0)
 	d.ClearDisplay()
 	Display = d
+	display = newSSD1306Display(d, i2c, 0x3C)
 
 	gpioPins = []machine.Pin{
 		machine.GPIO28,
@@ -16,6 +16,7 @@ var (
 )
 
 type device struct {
+	display  *ssd1306Display
 	gpioPins []machine.Pin
 	state    []State
 	cycle    []int
@@ -36,7 +37,7 @@ const (
 )
 
 func (z *device) GetDisplay() koebiten.Displayer {
-	return Display
+	return z.display
 }
 
 func (z *device) Init() error {
@@ -55,6 +56,7 @@ func (z *device) Init() error {
 	})
 	d.ClearDisplay()
 	Display = d
+	z.display = newSSD1306Display(d, i2c, 0x3C)
 
 	gpioPins = []machine.Pin{
 		machine.GPIO4,  // up
@@ -0,0 +1,145 @@
+//go:build !tinygo
+
+package hardware
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/sago35/koebiten"
+)
+
+// Device is a headless device used when building with the standard Go
+// toolchain. It has no physical buttons or screen, which makes it suitable for
+// running games on a CI machine.
+var Device = NewHeadless(128, 64)
+
+// Headless is a Hardware without a board attached.
+// Keys are driven from code with Press and Release, and the screen is an
+// in-memory framebuffer.
+type Headless struct {
+	display *Display
+	keys    [koebiten.KeyMax]bool
+	keybuf  [1]koebiten.Key
+}
+
+// NewHeadless creates a headless device whose display has the given size.
+func NewHeadless(width, height int) *Headless {
+	return &Headless{
+		display: NewDisplay(width, height),
+	}
+}
+
+func (z *Headless) Init() error {
+	return nil
+}
+
+func (z *Headless) GetDisplay() koebiten.Displayer {
+	return z.display
+}
+
+// Display returns the framebuffer of the device.
+func (z *Headless) Display() *Display {
+	return z.display
+}
+
+func (z *Headless) KeyUpdate() error {
+	buf := z.keybuf[:]
+	for k, pressed := range z.keys {
+		buf[0] = koebiten.Key(k)
+		if pressed {
+			koebiten.AppendPressedKeys(buf)
+		} else {
+			koebiten.AppendJustReleasedKeys(buf)
+		}
+	}
+	return nil
+}
+
+// Press holds down the given key until Release is called.
+func (z *Headless) Press(key koebiten.Key) {
+	z.keys[key] = true
+}
+
+// Release releases the given key.
+func (z *Headless) Release(key koebiten.Key) {
+	z.keys[key] = false
+}
+
+// Display is an in-memory framebuffer that implements koebiten.Displayer.
+//
+// Drawing goes to a back buffer, and Display copies it to the front buffer,
+// which holds the last presented frame.
+type Display struct {
+	back   *image.RGBA
+	front  *image.RGBA
+	frames int
+}
+
+// NewDisplay creates a framebuffer of the given size.
+func NewDisplay(w, h int) *Display {
+	r := image.Rect(0, 0, w, h)
+	d := &Display{
+		back:  image.NewRGBA(r),
+		front: image.NewRGBA(r),
+	}
+	d.ClearBuffer()
+	d.ClearDisplay()
+	return d
+}
+
+func (d *Display) Size() (x, y int16) {
+	b := d.back.Bounds()
+	return int16(b.Dx()), int16(b.Dy())
+}
+
+func (d *Display) SetPixel(x, y int16, c color.RGBA) {
+	d.back.SetRGBA(int(x), int(y), c)
+}
+
+// PixelAt returns the color of the pixel at the given x and y coordinates in
+// the back buffer.
+//
+// It implements the koebiten.PixelReader interface.
+func (d *Display) PixelAt(x, y int16) color.RGBA {
+	if !(image.Point{int(x), int(y)}.In(d.back.Rect)) {
+		return black
+	}
+	return d.back.RGBAAt(int(x), int(y))
+}
+
+func (d *Display) Display() error {
+	copy(d.front.Pix, d.back.Pix)
+	d.frames++
+	return nil
+}
+
+func (d *Display) ClearDisplay() {
+	fill(d.front, black)
+}
+
+func (d *Display) ClearBuffer() {
+	fill(d.back, black)
+}
+
+// Image returns the last frame presented by Display.
+// The returned image is updated in place on the next call to Display.
+func (d *Display) Image() *image.RGBA {
+	return d.front
+}
+
+// Frames returns how many times Display has been called.
+func (d *Display) Frames() int {
+	return d.frames
+}
+
+func fill(img *image.RGBA, c color.RGBA) {
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i+0] = c.R
+		img.Pix[i+1] = c.G
+		img.Pix[i+2] = c.B
+		img.Pix[i+3] = c.A
+	}
+}
+
+var black = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}
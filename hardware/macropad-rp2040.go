@@ -12,7 +12,7 @@ import (
 var Device = &device{}
 
 type device struct {
-	display  *sh1106.Device
+	display  *sh1106Display
 	gpioPins []machine.Pin
 	state    []State
 	cycle    []int
@@ -46,7 +46,7 @@ func (z *device) Init() error {
 		Height: 64,
 	})
 	d.ClearDisplay()
-	z.display = &d
+	z.display = newSH1106Display(&d)
 
 	gpioPins := []machine.Pin{
 		machine.KEY1,
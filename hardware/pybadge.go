@@ -51,7 +51,7 @@ func (z *device) Init() error {
 	})
 
 	d.FillScreen(color.RGBA{0, 0, 0, 255})
-	z.display = InitDisplay(&d, 128, 64)
+	z.display = InitDisplay(&d, 160, 128)
 
 	z.buttons = shifter.NewButtons()
 	z.buttons.Configure()
@@ -139,6 +139,8 @@ func (z *device) KeyUpdate() error {
 type Display struct {
 	d   *st7735.Device
 	img pixel.Image[pixel.RGB565BE]
+
+	partial partialUpdater
 }
 
 func InitDisplay(dev *st7735.Device, width, height int) *Display {
@@ -154,44 +156,42 @@ func InitDisplay(dev *st7735.Device, width, height int) *Display {
 	return d
 }
 
+// Size returns the size of the framebuffer.
+// The logical screen of the game is scaled to fit into it by koebiten.
 func (d *Display) Size() (x, y int16) {
-	return 128, 64
+	w, h := d.img.Size()
+	return int16(w), int16(h)
 }
 
 func (d *Display) SetPixel(x, y int16, c color.RGBA) {
 	mx, my := d.Size()
-	if 0 <= x && x < int16(mx) && 0 <= y && y < int16(my) {
-		d.img.Set(int(x), int(y), pixelWhite)
-	}
-	return
-	cnt := 0
-	if c.R < 0x80 {
-		cnt++
-	}
-	if c.G < 0x80 {
-		cnt++
+	if 0 <= x && x < mx && 0 <= y && y < my {
+		d.img.Set(int(x), int(y), pixel.NewColor[pixel.RGB565BE](c.R, c.G, c.B))
 	}
-	if c.B < 0x80 {
-		cnt++
-	}
-	if cnt >= 2 {
-		d.img.Set(int(x), int(y), pixelWhite)
-	} else {
-		d.img.Set(int(x), int(y), pixelBlack)
+}
+
+func (d *Display) PixelAt(x, y int16) color.RGBA {
+	mx, my := d.Size()
+	if 0 <= x && x < mx && 0 <= y && y < my {
+		return d.img.Get(int(x), int(y)).RGBA()
 	}
-	//d.d.SetPixel(x, y, c)
+	return black
 }
 
+// Display sends the regions of the framebuffer that changed since the
+// previous frame to the screen.
 func (d *Display) Display() error {
 	ox, oy := d.getImageTopLeftForCentering()
-	return d.d.DrawBitmap(int16(ox), int16(oy), d.img)
+	return d.partial.display(d.d, d.img, ox, oy)
 }
 
 func (d *Display) ClearBuffer() {
 	d.img.FillSolidColor(pixelBlack)
 }
 
+// ClearDisplay makes the next Display send the whole framebuffer.
 func (d *Display) ClearDisplay() {
+	d.partial.invalidate()
 }
 
 func (d *Display) getImageTopLeftForCentering() (int16, int16) {
@@ -205,6 +205,5 @@ var (
 	white = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
 	black = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}
 
-	pixelWhite = pixel.NewColor[pixel.RGB565BE](0xFF, 0xFF, 0xFF)
 	pixelBlack = pixel.NewColor[pixel.RGB565BE](0x00, 0x00, 0x00)
 )
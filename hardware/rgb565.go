@@ -0,0 +1,70 @@
+//go:build tinygo && (gopher_badge || gopher_board_spi || pybadge || wioterminal)
+
+package hardware
+
+import (
+	"image"
+
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers/pixel"
+)
+
+// bitmapDrawer is implemented by the TFT drivers that can draw an image into
+// a window of the screen.
+type bitmapDrawer interface {
+	DrawBitmap(x, y int16, bitmap pixel.Image[pixel.RGB565BE]) error
+}
+
+// partialUpdater sends only the changed regions of an RGB565 framebuffer.
+type partialUpdater struct {
+	tracker *koebiten.DirtyTracker
+	rects   []image.Rectangle
+	scratch []uint8
+}
+
+// chunkRows is the number of rows sent at once for regions that are narrower
+// than the framebuffer.
+const chunkRows = 16
+
+// invalidate makes the next call to display send the whole framebuffer.
+func (p *partialUpdater) invalidate() {
+	if p.tracker != nil {
+		p.tracker.Invalidate()
+	}
+}
+
+// display sends the regions of img that changed since the last call to dev,
+// with the top-left corner of img at (ox, oy).
+func (p *partialUpdater) display(dev bitmapDrawer, img pixel.Image[pixel.RGB565BE], ox, oy int16) error {
+	w, h := img.Size()
+	if p.tracker == nil {
+		p.tracker = koebiten.NewDirtyTracker(w, h, 16)
+		p.scratch = make([]uint8, w*chunkRows*2)
+	}
+	buf := img.RawBuffer()
+	stride := w * 2
+	p.rects = p.tracker.Update(buf, p.rects)
+	for _, r := range p.rects {
+		if r.Dx() == w {
+			// Full rows are contiguous in the framebuffer.
+			sub := pixel.NewImageFromBytes[pixel.RGB565BE](w, r.Dy(), buf[r.Min.Y*stride:r.Max.Y*stride])
+			if err := dev.DrawBitmap(ox, oy+int16(r.Min.Y), sub); err != nil {
+				return err
+			}
+			continue
+		}
+		for y := r.Min.Y; y < r.Max.Y; y += chunkRows {
+			rows := min(chunkRows, r.Max.Y-y)
+			n := r.Dx() * 2
+			for i := 0; i < rows; i++ {
+				start := (y+i)*stride + r.Min.X*2
+				copy(p.scratch[i*n:(i+1)*n], buf[start:start+n])
+			}
+			sub := pixel.NewImageFromBytes[pixel.RGB565BE](r.Dx(), rows, p.scratch[:n*rows])
+			if err := dev.DrawBitmap(ox+int16(r.Min.X), oy+int16(y), sub); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+//go:build tinygo && macropad_rp2040
+
+package hardware
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers/sh1106"
+)
+
+// sh1106Display wraps an SH1106 and sends only the changed regions of the
+// framebuffer in Display. The driver doesn't expose its buffer, so the
+// wrapper keeps its own in the same page layout.
+type sh1106Display struct {
+	*sh1106.Device
+	width   int16
+	height  int16
+	buffer  []byte
+	tracker *koebiten.DirtyTracker
+	rects   []image.Rectangle
+}
+
+// sh1106ColumnOffset is the offset of the 128 visible columns in the 132
+// columns of the SH1106 memory.
+const sh1106ColumnOffset = 2
+
+func newSH1106Display(dev *sh1106.Device) *sh1106Display {
+	w, h := dev.Size()
+	return &sh1106Display{
+		Device:  dev,
+		width:   w,
+		height:  h,
+		buffer:  make([]byte, int(w)*int(h)/8),
+		tracker: koebiten.NewPagedDirtyTracker(int(w), int(h)),
+	}
+}
+
+func (d *sh1106Display) SetPixel(x, y int16, c color.RGBA) {
+	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+		return
+	}
+	i := int(x) + int(y/8)*int(d.width)
+	if c.R != 0 || c.G != 0 || c.B != 0 {
+		d.buffer[i] |= 1 << uint8(y%8)
+	} else {
+		d.buffer[i] &^= 1 << uint8(y%8)
+	}
+}
+
+func (d *sh1106Display) PixelAt(x, y int16) color.RGBA {
+	if x < 0 || x >= d.width || y < 0 || y >= d.height {
+		return black
+	}
+	if d.buffer[int(x)+int(y/8)*int(d.width)]&(1<<uint8(y%8)) != 0 {
+		return white
+	}
+	return black
+}
+
+func (d *sh1106Display) ClearBuffer() {
+	clear(d.buffer)
+}
+
+// Display sends the pages of the framebuffer that changed since the previous
+// frame, one page of each region at a time. Display always returns nil: the
+// Command and Tx methods of the driver don't report the errors of the bus.
+func (d *sh1106Display) Display() error {
+	w := int(d.width)
+	d.rects = d.tracker.Update(d.buffer, d.rects)
+	for _, r := range d.rects {
+		col := uint8(r.Min.X + sh1106ColumnOffset)
+		for page := r.Min.Y / 8; page < r.Max.Y/8; page++ {
+			d.Command(0xB0 | uint8(page&0x07)) // SET_PAGE_ADDR
+			d.Command(sh1106.SETLOWCOLUMN | col&0x0F)
+			d.Command(sh1106.SETHIGHCOLUMN | col>>4)
+			d.Tx(d.buffer[page*w+r.Min.X:page*w+r.Max.X], false)
+		}
+	}
+	return nil
+}
+
+func (d *sh1106Display) ClearDisplay() {
+	d.ClearBuffer()
+	d.Device.ClearDisplay()
+	d.tracker.Invalidate()
+}
+
+var (
+	white = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	black = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}
+)
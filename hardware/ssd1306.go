@@ -0,0 +1,76 @@
+//go:build tinygo && (zero_kb02 || gopher_board_i2c || conf2025badge)
+
+package hardware
+
+import (
+	"image"
+	"image/color"
+
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers"
+	"tinygo.org/x/drivers/ssd1306"
+)
+
+// ssd1306Display wraps an SSD1306 connected with I2C and sends only the
+// changed regions of the framebuffer in Display.
+type ssd1306Display struct {
+	*ssd1306.Device
+	bus     drivers.I2C
+	address uint16
+	tracker *koebiten.DirtyTracker
+	rects   []image.Rectangle
+	tx      []byte
+}
+
+func newSSD1306Display(dev *ssd1306.Device, bus drivers.I2C, address uint16) *ssd1306Display {
+	w, h := dev.Size()
+	return &ssd1306Display{
+		Device:  dev,
+		bus:     bus,
+		address: address,
+		tracker: koebiten.NewPagedDirtyTracker(int(w), int(h)),
+		tx:      make([]byte, int(w)+1),
+	}
+}
+
+func (d *ssd1306Display) PixelAt(x, y int16) color.RGBA {
+	if d.GetPixel(x, y) {
+		return white
+	}
+	return black
+}
+
+// Display sends the pages of the framebuffer that changed since the previous
+// frame. The column and page window is set for each region, and the display
+// advances to the next page at the end of each row of the window.
+func (d *ssd1306Display) Display() error {
+	w, _ := d.Size()
+	buf := d.GetBuffer()
+	d.rects = d.tracker.Update(buf, d.rects)
+	for _, r := range d.rects {
+		d.Command(ssd1306.COLUMNADDR)
+		d.Command(uint8(r.Min.X))
+		d.Command(uint8(r.Max.X - 1))
+		d.Command(ssd1306.PAGEADDR)
+		d.Command(uint8(r.Min.Y / 8))
+		d.Command(uint8(r.Max.Y/8 - 1))
+		for page := r.Min.Y / 8; page < r.Max.Y/8; page++ {
+			d.tx[0] = 0x40 // data mode
+			n := copy(d.tx[1:], buf[page*int(w)+r.Min.X:page*int(w)+r.Max.X])
+			if err := d.bus.Tx(d.address, d.tx[:n+1], nil); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (d *ssd1306Display) ClearDisplay() {
+	d.Device.ClearDisplay()
+	d.tracker.Invalidate()
+}
+
+var (
+	white = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	black = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xFF}
+)
@@ -21,7 +21,7 @@ func (z ZERO_KB02) Init() error {
 }
 
 func (z ZERO_KB02) GetDisplay() koebiten.Displayer {
-	return Display
+	return display
 }
 
 func (z ZERO_KB02) KeyUpdate() error {
@@ -30,6 +30,7 @@ func (z ZERO_KB02) KeyUpdate() error {
 
 var (
 	Display *ssd1306.Device
+	display *ssd1306Display
 )
 
 var (
@@ -99,6 +100,7 @@ func Init() error {
 	d.SetRotation(drivers.Rotation180)
 	d.ClearDisplay()
 	Display = d
+	display = newSSD1306Display(d, i2c, 0x3C)
 
 	gpioPins = []machine.Pin{
 		machine.GPIO2, // rotary
@@ -1,42 +1,141 @@
 package koebiten
 
 import (
+	"image"
 	"image/color"
+	"image/draw"
 	"io/fs"
 
 	"github.com/chewxy/math32"
 	"tinygo.org/x/drivers/image/png"
-	"tinygo.org/x/drivers/pixel"
 )
 
-var _ Displayer = (*Image)(nil)
+var (
+	_ Displayer   = (*Image)(nil)
+	_ PixelReader = (*Image)(nil)
+	_ draw.Image  = (*Image)(nil)
+)
 
-// Image is a Wrapper for the pixel.Image type.
+// Image is an image whose pixels are stored in one of the pixel formats.
 //
-// Image implements the Displayer interface.
+// Image implements the Displayer interface, and the image.Image and
+// draw.Image interfaces of the standard library. The coordinates of SetPixel
+// and PixelAt are relative to the top-left corner of the image, while At and
+// Set use the coordinates of Bounds like the standard library. They are the
+// same unless the image is a sub-image.
 type Image struct {
-	img pixel.Image[pixel.Monochrome]
+	format PixelFormat
+	buf    pixelBuffer
+
+	// rect is the area of buf covered by the image. It is smaller than buf
+	// for a sub-image.
+	rect image.Rectangle
 }
 
 // Size returns the width and height of the image.
 //
 // It implements the Displayer interface.
 func (i *Image) Size() (int16, int16) {
-	x, y := i.img.Size()
-	return int16(x), int16(y)
+	return int16(i.rect.Dx()), int16(i.rect.Dy())
+}
+
+// size returns the width and height of the image as ints.
+func (i *Image) size() (int, int) {
+	return i.rect.Dx(), i.rect.Dy()
+}
+
+// at returns the color of the pixel at (x, y) relative to the top-left corner
+// of the image. The coordinates must be inside the image.
+func (i *Image) at(x, y int) color.RGBA {
+	return i.buf.rgbaAt(i.rect.Min.X+x, i.rect.Min.Y+y)
+}
+
+// set sets the pixel at (x, y) relative to the top-left corner of the image.
+// The coordinates must be inside the image.
+func (i *Image) set(x, y int, c color.RGBA) {
+	i.buf.setRGBA(i.rect.Min.X+x, i.rect.Min.Y+y, c)
+}
+
+// SubImage returns an image representing the portion of the image i visible
+// through r. The returned image shares pixels with the original image, so
+// drawing to one changes the other.
+//
+// r is in the coordinates of the original image of i, as returned by Bounds.
+// If r is outside of i, the returned image is empty.
+func (i *Image) SubImage(r image.Rectangle) *Image {
+	return &Image{
+		format: i.format,
+		buf:    i.buf,
+		rect:   r.Intersect(i.rect),
+	}
+}
+
+// Bounds returns the area of the image in the coordinates of its original
+// image. It starts at (0, 0) unless the image is a sub-image.
+//
+// It implements the image.Image interface.
+func (i *Image) Bounds() image.Rectangle {
+	return i.rect
+}
+
+// ColorModel returns the color model of the pixel format of the image.
+//
+// It implements the image.Image interface.
+func (i *Image) ColorModel() color.Model {
+	return i.format.Model()
+}
+
+// At returns the color of the pixel at (x, y) in the coordinates of Bounds.
+// If (x, y) is outside the image, it returns a transparent color.
+//
+// It implements the image.Image interface.
+func (i *Image) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(i.rect)) {
+		return color.RGBA{}
+	}
+	return i.buf.rgbaAt(x, y)
+}
+
+// Set sets the pixel at (x, y) in the coordinates of Bounds to c, converted
+// to the pixel format of the image.
+// If (x, y) is outside the image, Set does nothing.
+//
+// It implements the draw.Image interface.
+func (i *Image) Set(x, y int, c color.Color) {
+	if !(image.Point{x, y}.In(i.rect)) {
+		return
+	}
+	i.buf.setRGBA(x, y, color.RGBAModel.Convert(c).(color.RGBA))
+}
+
+// Format returns the pixel format of the image.
+func (i *Image) Format() PixelFormat {
+	return i.format
 }
 
 // SetPixel sets the pixel at the given x and y coordinates to the given color.
-// The color is converted to a pixel.Monochrome color.
+// The color is converted to the pixel format of the image.
 // If the x and y coordinates are outside the image, the function does nothing.
 //
 // It implements the Displayer interface.
 func (i *Image) SetPixel(x, y int16, c color.RGBA) {
-	w, h := i.img.Size()
-	if x < 0 || x >= int16(w) || y < 0 || y >= int16(h) {
+	w, h := i.size()
+	if x < 0 || int(x) >= w || y < 0 || int(y) >= h {
 		return
 	}
-	i.img.Set(int(x), int(y), pixel.NewMonochrome(c.R, c.G, c.B))
+	i.set(int(x), int(y), c)
+}
+
+// PixelAt returns the color of the pixel at the given x and y coordinates.
+// If the x and y coordinates are outside the image, it returns black.
+//
+// It implements the PixelReader interface.
+func (i *Image) PixelAt(x, y int16) color.RGBA {
+	w, h := i.size()
+	if x < 0 || int(x) >= w || y < 0 || int(y) >= h {
+		return black
+	}
+	return i.at(int(x), int(y))
 }
 
 // Display does nothing.
@@ -54,81 +153,287 @@ func (i *Image) ClearDisplay() {}
 // It implements the Displayer interface.
 func (i *Image) ClearBuffer() {}
 
-// NewImage creates a new Image with the given width and height.
+// NewImage creates a new monochrome Image with the given width and height.
 //
 // It returns a pointer to the new Image.
 func NewImage(width, height int16) *Image {
+	return NewImageWithFormat(width, height, FormatMonochrome)
+}
+
+// NewImageWithFormat creates a new Image with the given width, height and
+// pixel format.
+func NewImageWithFormat(width, height int16, format PixelFormat) *Image {
 	return &Image{
-		img: pixel.NewImage[pixel.Monochrome](int(width), int(height)),
+		format: format,
+		buf:    newPixelBuffer(format, int(width), int(height)),
+		rect:   image.Rect(0, 0, int(width), int(height)),
+	}
+}
+
+// NewImageFromImageOptions represents options for NewImageFromImage and
+// NewImageFromFSWithOptions.
+type NewImageFromImageOptions struct {
+	// Format is the pixel format of the new image.
+	// The default (zero) value is FormatMonochrome.
+	Format PixelFormat
+
+	// Dither is the dithering used to reduce the colors to the levels of a
+	// monochrome or grayscale format. It is ignored by FormatRGB565.
+	// The default (zero) value is DitherNone.
+	Dither Dither
+
+	// Threshold points to the luminance from which a pixel is light, with
+	// DitherNone and FormatMonochrome.
+	// The default (zero) value is nil, which means 0x80.
+	Threshold *uint8
+
+	// Invert inverts the luminance before it is reduced. With
+	// FormatMonochrome, the light pixels are set instead of the dark ones.
+	// With the grayscale formats, the image is a negative. It is ignored by
+	// FormatRGB565.
+	// The default (zero) value is false.
+	Invert bool
+}
+
+// NewImageFromImage creates a new Image with the same pixels as img.
+// The bounds of the new image start at (0, 0).
+// If options is nil, the default options are used.
+//
+// For a monochrome or grayscale format, the luminance of the pixels is
+// reduced to the levels of the format. The dark pixels of a monochrome image
+// are set by default, like NewImageFromFS. The transparent pixels of img are
+// black.
+func NewImageFromImage(img image.Image, options *NewImageFromImageOptions) *Image {
+	if options == nil {
+		options = &NewImageFromImageOptions{}
+	}
+	b := img.Bounds()
+	i := NewImageWithFormat(int16(b.Dx()), int16(b.Dy()), options.Format)
+	if options.Format.levels() == 0 {
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				i.set(x-b.Min.X, y-b.Min.Y, color.RGBAModel.Convert(img.At(x, y)).(color.RGBA))
+			}
+		}
+		return i
+	}
+
+	lum := make([]uint8, b.Dx()*b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			lum[(y-b.Min.Y)*b.Dx()+x-b.Min.X] = luminance(color.RGBAModel.Convert(img.At(x, y)).(color.RGBA))
+		}
 	}
+	quantizeLuma(i, lum, b.Dx(), options)
+	return i
 }
 
-// NewImageFromFS creates a new Image from the filesystem.
+// NewImageFromFS creates a new monochrome Image from the filesystem.
+//
+// Dark pixels of the PNG image are set and the others are cleared.
 func NewImageFromFS(fsys fs.FS, path string) *Image {
-	img, err := loadImageFromFS(fsys, path)
+	return NewImageFromFSWithFormat(fsys, path, FormatMonochrome)
+}
+
+// NewImageFromFSWithOptions creates a new Image from a PNG image in the
+// filesystem.
+//
+// The colors are converted as by NewImageFromImage, so the dark pixels of a
+// monochrome image are set by default, like NewImageFromFS. Use Dither to
+// import shaded art to a monochrome or grayscale image.
+// If options is nil, the default options are used.
+func NewImageFromFSWithOptions(fsys fs.FS, path string, options *NewImageFromImageOptions) *Image {
+	img, err := loadImageFromFSWithOptions(fsys, path, options)
+	if err != nil {
+		panic(err)
+	}
+	return img
+}
+
+// loadImageFromFSWithOptions loads an image from the filesystem as
+// NewImageFromFSWithOptions does.
+func loadImageFromFSWithOptions(fsys fs.FS, path string, options *NewImageFromImageOptions) (*Image, error) {
+	if options == nil {
+		options = &NewImageFromImageOptions{}
+	}
+	var img *Image
+	var lum []uint8
+	err := decodePNG(fsys, path, func(width, height int) {
+		img = NewImageWithFormat(int16(width), int16(height), options.Format)
+		if options.Format.levels() > 0 {
+			lum = make([]uint8, width*height)
+		}
+	}, func(x, y int, c color.RGBA) {
+		if lum == nil {
+			img.set(x, y, c)
+			return
+		}
+		w, _ := img.size()
+		lum[y*w+x] = luminance(c)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if lum != nil {
+		w, _ := img.size()
+		quantizeLuma(img, lum, w, options)
+	}
+	return img, nil
+}
+
+// NewImageFromFSWithFormat creates a new Image with the given pixel format
+// from the filesystem.
+//
+// With FormatMonochrome, dark pixels of the PNG image are set and the others
+// are cleared, like NewImageFromFS. With the other formats, the colors of the
+// PNG image are converted to the format.
+func NewImageFromFSWithFormat(fsys fs.FS, path string, format PixelFormat) *Image {
+	img, err := loadImageFromFS(fsys, path, format)
 	if err != nil {
 		panic(err)
 	}
-	return &Image{img: img}
+	return img
 }
 
 // loadImageFromFS loads an image from the filesystem.
-func loadImageFromFS(fsys fs.FS, path string) (pixel.Image[pixel.Monochrome], error) {
+//
+// With FormatMonochrome, the pixels that have at least two dark channels are
+// set.
+func loadImageFromFS(fsys fs.FS, path string, format PixelFormat) (*Image, error) {
+	var img *Image
+	err := decodePNG(fsys, path, func(width, height int) {
+		img = NewImageWithFormat(int16(width), int16(height), format)
+	}, func(x, y int, c color.RGBA) {
+		if format != FormatMonochrome {
+			img.set(x, y, c)
+			return
+		}
+		cnt := 0
+		if c.R < 0x80 {
+			cnt++
+		}
+		if c.G < 0x80 {
+			cnt++
+		}
+		if c.B < 0x80 {
+			cnt++
+		}
+		if cnt >= 2 {
+			img.set(x, y, white)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// decodePNG decodes a PNG image from the filesystem. size is called with the
+// size of the image, then set is called for every pixel.
+func decodePNG(fsys fs.FS, path string, size func(width, height int), set func(x, y int, c color.RGBA)) (err error) {
+	// The decoder may panic on images it doesn't support. The panics of size
+	// and set are not errors of the image, so they go on.
+	inCallback := false
+	defer func() {
+		if r := recover(); r != nil {
+			if inCallback {
+				panic(r)
+			}
+			err = ErrInvalidPNG
+		}
+	}()
+
 	var buffer [3 * 8 * 8 * 4]uint16
 	p, err := fsys.Open(path)
 	if err != nil {
-		return pixel.Image[pixel.Monochrome]{}, err
+		return err
 	}
+	defer p.Close()
 
-	var img pixel.Image[pixel.Monochrome]
+	started := false
 	png.SetCallback(buffer[:], func(data []uint16, x, y, w, h, width, height int16) {
-		if img.Len() == 0 {
-			img = pixel.NewImage[pixel.Monochrome](int(width), int(height))
+		inCallback = true
+		if !started {
+			size(int(width), int(height))
+			started = true
 		}
 
 		for yy := int16(0); yy < h; yy++ {
 			for xx := int16(0); xx < w; xx++ {
-				c := C565toRGBA(data[yy*w+xx])
-				cnt := 0
-				if c.R < 0x80 {
-					cnt++
-				}
-				if c.G < 0x80 {
-					cnt++
-				}
-				if c.B < 0x80 {
-					cnt++
-				}
-				if cnt >= 2 {
-					img.Set(int(x+xx), int(y+yy), true)
-				}
+				set(int(x+xx), int(y+yy), C565toRGBA(data[yy*w+xx]))
 			}
 		}
+		inCallback = false
 	})
 
 	if _, err = png.Decode(p); err != nil {
-		return pixel.Image[pixel.Monochrome]{}, err
+		return err
 	}
-
-	return img, nil
+	if !started {
+		size(0, 0)
+	}
+	return nil
 }
 
 // Fill fills the image with the given color.
+// The color is converted to the pixel format of the image.
 func (i *Image) Fill(clr color.Color) {
-	r, g, b, _ := clr.RGBA()
-	w, h := i.img.Size()
+	c := color.RGBAModel.Convert(clr).(color.RGBA)
+	if w, h := i.buf.size(); i.rect == image.Rect(0, 0, w, h) {
+		i.buf.fill(c)
+		return
+	}
+	w, h := i.size()
 	for y := 0; y < h; y++ {
 		for x := 0; x < w; x++ {
-			i.img.Set(x, y, pixel.NewMonochrome(uint8(r), uint8(g), uint8(b)))
+			i.set(x, y, c)
 		}
 	}
 }
 
+// DrawImageOptions represents options for DrawImage.
 type DrawImageOptions struct {
+	// GeoM is a geometry matrix to draw.
+	// The default (zero) value is identity, which draws the image at (0, 0).
 	GeoM GeoM
+
+	// ColorScale is a scale of color applied to the pixels of the image.
+	// The alpha scale makes the image translucent.
+	// The default (zero) value is identity.
+	ColorScale ColorScale
+
+	// CompositeMode is a composite mode to draw.
+	// The default (zero) value is CompositeModeSourceOver.
+	CompositeMode CompositeMode
+
+	// ColorKey is the color of the transparent pixels of the image.
+	// The default (zero) value is nil, which means that the cleared pixels of
+	// a monochrome image are transparent and the pixels of the other formats
+	// are all opaque.
+	ColorKey color.Color
+
+	// Filter is a type of texture filter used when GeoM scales or rotates
+	// the image.
+	// The default (zero) value is FilterNearest.
+	Filter Filter
+
+	// Mask is an alpha mask of the same size as the image. The luminance of
+	// each pixel of the mask is the opacity of the pixel of the image at the
+	// same position, so the set pixels of a monochrome mask are opaque.
+	// The default (zero) value is nil, which means no mask.
+	Mask *Image
 }
 
 // DrawImage draws an image onto the display.
+//
+// The pixels are converted to the format of dst by its SetPixel method.
+// By default, only the set pixels of a monochrome image are drawn, in white,
+// and every pixel of the other formats is drawn with its color. The options
+// change which pixels are transparent and how they are combined with dst.
+//
+// If GeoM only translates the image, it is drawn at the rounded position.
+// Otherwise the pixels of dst covered by the image are mapped back to the
+// image with the inverted GeoM and sampled with the filter of the options.
 func (i *Image) DrawImage(dst Displayer, options DrawImageOptions) {
 	if isNil(dst) {
 		dst = display
@@ -139,25 +444,17 @@ func (i *Image) DrawImage(dst Displayer, options DrawImageOptions) {
 		return
 	}
 
-	w, h := i.img.Size()
+	c := newCompositor(dst, i, &options)
+	w, h := i.size()
 	if geoM.a_1 == 0 && geoM.b == 0 && geoM.c == 0 && geoM.d_1 == 0 {
 		tx, ty := geoM.Apply(0, 0)
 		ox, oy := int(math32.Round(tx)), int(math32.Round(ty))
 		for yy := 0; yy < h; yy++ {
 			for xx := 0; xx < w; xx++ {
-				if i.img.Get(xx, yy) == true {
-					dst.SetPixel(int16(xx+ox), int16(yy+oy), white)
-				}
+				c.draw(int16(xx+ox), int16(yy+oy), xx, yy, i.at(xx, yy))
 			}
 		}
 	} else {
-		for yy := 0; yy < h; yy++ {
-			for xx := 0; xx < w; xx++ {
-				if i.img.Get(xx, yy) == true {
-					xxf, yyf := geoM.Apply(float32(xx), float32(yy))
-					dst.SetPixel(int16(math32.Round(xxf)), int16(math32.Round(yyf)), white)
-				}
-			}
-		}
+		drawTransformed(&c, i, geoM, options.Filter)
 	}
 }
@@ -0,0 +1,297 @@
+package koebiten_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sago35/koebiten"
+	"github.com/sago35/koebiten/hardware"
+	"tinygo.org/x/drivers/pixel"
+)
+
+func TestImageFormats(t *testing.T) {
+	orange := color.RGBA{R: 0xF8, G: 0x80, B: 0x00, A: 0xFF}
+	gray := color.RGBA{R: 0x80, G: 0x80, B: 0x80, A: 0xFF}
+
+	tests := []struct {
+		format koebiten.PixelFormat
+		in     color.RGBA
+		want   color.RGBA
+	}{
+		{koebiten.FormatRGB565, orange, color.RGBA{R: 0xFF, G: 0x82, B: 0x00, A: 0xFF}},
+		{koebiten.FormatGray4, gray, color.RGBA{R: 0x88, G: 0x88, B: 0x88, A: 0xFF}},
+		{koebiten.FormatGray2, gray, color.RGBA{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF}},
+		{koebiten.FormatGray2, orange, color.RGBA{R: 0xAA, G: 0xAA, B: 0xAA, A: 0xFF}},
+	}
+	for _, tt := range tests {
+		img := koebiten.NewImageWithFormat(5, 3, tt.format)
+		if got := img.Format(); got != tt.format {
+			t.Errorf("Format() = %v, want %v", got, tt.format)
+		}
+		img.SetPixel(3, 1, tt.in)
+
+		// Blit onto a color display: the pixel keeps the color of the format.
+		d := hardware.NewHeadless(8, 8).Display()
+		op := koebiten.DrawImageOptions{}
+		op.GeoM.Translate(2, 2)
+		img.DrawImage(d, op)
+		d.Display()
+		if got := d.Image().RGBAAt(5, 3); got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.format, got, tt.want)
+		}
+	}
+}
+
+func TestImageFill(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	orange := color.RGBA{R: 0xFF, G: 0x82, B: 0x00, A: 0xFF}
+	tests := []struct {
+		format koebiten.PixelFormat
+		c      color.RGBA
+	}{
+		// 9 pixels don't fill whole bytes of a monochrome image.
+		{koebiten.FormatMonochrome, white},
+		{koebiten.FormatRGB565, orange},
+	}
+	for _, tt := range tests {
+		img := koebiten.NewImageWithFormat(3, 3, tt.format)
+		img.Fill(tt.c)
+		for y := int16(0); y < 3; y++ {
+			for x := int16(0); x < 3; x++ {
+				if got := img.PixelAt(x, y); got != tt.c {
+					t.Errorf("%v: pixel (%d, %d) = %v, want %v", tt.format, x, y, got, tt.c)
+				}
+			}
+		}
+	}
+}
+
+func TestNewImageFromFSPolarity(t *testing.T) {
+	// A black pixel at (0, 0) on white.
+	fsys := fstest.MapFS{"a.png": pngFile(t, 2, 1)}
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	black := color.RGBA{A: 0xFF}
+	dark := [2]color.RGBA{white, black}
+	light := [2]color.RGBA{black, white}
+
+	d := hardware.NewHeadless(2, 1).Display()
+	koebiten.DrawImageFSWithOptions(d, fsys, "a.png", koebiten.DrawImageFSOptions{
+		ImageOptions: &koebiten.NewImageFromImageOptions{},
+	})
+	d.Display()
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want [2]color.RGBA
+	}{
+		{"NewImageFromFS", koebiten.NewImageFromFS(fsys, "a.png"), dark},
+		{"NewImageFromFSWithFormat", koebiten.NewImageFromFSWithFormat(fsys, "a.png", koebiten.FormatMonochrome), dark},
+		{"NewImageFromFSWithOptions", koebiten.NewImageFromFSWithOptions(fsys, "a.png", nil), dark},
+		{"NewImageFromFSWithOptions zero", koebiten.NewImageFromFSWithOptions(fsys, "a.png", &koebiten.NewImageFromImageOptions{}), dark},
+		{"NewImageFromFSWithOptions inverted", koebiten.NewImageFromFSWithOptions(fsys, "a.png", &koebiten.NewImageFromImageOptions{Invert: true}), light},
+		{"DrawImageFSWithOptions", d.Image(), dark},
+	}
+	for _, tt := range tests {
+		for x, want := range tt.want {
+			if got := color.RGBAModel.Convert(tt.img.At(x, 0)); got != want {
+				t.Errorf("%s: pixel %d = %v, want %v", tt.name, x, got, want)
+			}
+		}
+	}
+}
+
+func TestImageFormatConversion(t *testing.T) {
+	src := koebiten.NewImageWithFormat(2, 1, koebiten.FormatRGB565)
+	src.SetPixel(0, 0, color.RGBA{R: 0xFF, G: 0xFF, B: 0x00, A: 0xFF})
+	src.SetPixel(1, 0, color.RGBA{R: 0x00, G: 0x00, B: 0x80, A: 0xFF})
+
+	dst := koebiten.NewImage(2, 1)
+	src.DrawImage(dst, koebiten.DrawImageOptions{})
+
+	// Drawing the monochrome image back sets only the light pixel.
+	d := hardware.NewHeadless(2, 1).Display()
+	dst.DrawImage(d, koebiten.DrawImageOptions{})
+	d.Display()
+	if got := d.Image().RGBAAt(0, 0); got.R != 0xFF {
+		t.Errorf("light pixel: got %v, want white", got)
+	}
+	if got := d.Image().RGBAAt(1, 0); got.R != 0 {
+		t.Errorf("dark pixel: got %v, want black", got)
+	}
+}
+
+func TestDrawImageCompositeModes(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	black := color.RGBA{A: 0xFF}
+
+	// The sprite has its left pixel set and its right pixel cleared.
+	sprite := koebiten.NewImage(2, 1)
+	sprite.SetPixel(0, 0, white)
+
+	tests := []struct {
+		name  string
+		op    koebiten.DrawImageOptions
+		bg    color.RGBA
+		left  color.RGBA
+		right color.RGBA
+	}{
+		{"source over", koebiten.DrawImageOptions{}, black, white, black},
+		{"copy", koebiten.DrawImageOptions{CompositeMode: koebiten.CompositeModeCopy}, white, white, black},
+		{"color key", koebiten.DrawImageOptions{ColorKey: white}, white, white, black},
+		{"xor", koebiten.DrawImageOptions{CompositeMode: koebiten.CompositeModeXor}, white, black, white},
+		{"and", koebiten.DrawImageOptions{CompositeMode: koebiten.CompositeModeAnd}, white, white, white},
+		{"invert", koebiten.DrawImageOptions{CompositeMode: koebiten.CompositeModeInvert}, black, white, black},
+	}
+	for _, tt := range tests {
+		dst := koebiten.NewImageWithFormat(2, 1, koebiten.FormatRGB565)
+		dst.Fill(tt.bg)
+		sprite.DrawImage(dst, tt.op)
+		if got := dst.PixelAt(0, 0); got != tt.left {
+			t.Errorf("%s: left pixel = %v, want %v", tt.name, got, tt.left)
+		}
+		if got := dst.PixelAt(1, 0); got != tt.right {
+			t.Errorf("%s: right pixel = %v, want %v", tt.name, got, tt.right)
+		}
+	}
+}
+
+func TestDrawImageColorScaleAndMask(t *testing.T) {
+	src := koebiten.NewImageWithFormat(2, 1, koebiten.FormatGray4)
+	src.Fill(color.White)
+	mask := koebiten.NewImage(2, 1)
+	mask.SetPixel(1, 0, color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF})
+
+	d := hardware.NewHeadless(2, 1).Display()
+	op := koebiten.DrawImageOptions{Mask: mask}
+	op.ColorScale.Scale(1, 0, 0, 0.5)
+	src.DrawImage(d, op)
+	d.Display()
+
+	if got := d.Image().RGBAAt(0, 0); got != (color.RGBA{A: 0xFF}) {
+		t.Errorf("masked pixel = %v, want black", got)
+	}
+	if got := d.Image().RGBAAt(1, 0); got != (color.RGBA{R: 0x80, A: 0xFF}) {
+		t.Errorf("blended pixel = %v, want half red", got)
+	}
+}
+
+func TestDrawImageTransformed(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	src := koebiten.NewImage(4, 4)
+	src.Fill(white)
+
+	count := func(img *koebiten.Image) int {
+		n := 0
+		w, h := img.Size()
+		for y := int16(0); y < h; y++ {
+			for x := int16(0); x < w; x++ {
+				if img.PixelAt(x, y) == white {
+					n++
+				}
+			}
+		}
+		return n
+	}
+
+	// Scaled up by 3, the image covers 12x12 pixels without holes.
+	dst := koebiten.NewImage(16, 16)
+	op := koebiten.DrawImageOptions{}
+	op.GeoM.Scale(3, 3)
+	src.DrawImage(dst, op)
+	if got := count(dst); got != 144 {
+		t.Errorf("scaled: %d pixels set, want 144", got)
+	}
+
+	// Rotated by 45 degrees around its center, the image has no holes inside.
+	dst = koebiten.NewImage(16, 16)
+	op = koebiten.DrawImageOptions{}
+	op.GeoM.Translate(-2, -2)
+	op.GeoM.Scale(2, 2)
+	op.GeoM.Rotate(math.Pi / 4)
+	op.GeoM.Translate(8, 8)
+	src.DrawImage(dst, op)
+	for y := int16(6); y < 10; y++ {
+		for x := int16(6); x < 10; x++ {
+			if dst.PixelAt(x, y) != white {
+				t.Errorf("rotated: pixel (%d, %d) is not set", x, y)
+			}
+		}
+	}
+
+	// Clipped to the destination.
+	dst = koebiten.NewImage(4, 4)
+	op = koebiten.DrawImageOptions{}
+	op.GeoM.Scale(2, 2)
+	op.GeoM.Translate(-2, -2)
+	src.DrawImage(dst, op)
+	if got := count(dst); got != 16 {
+		t.Errorf("clipped: %d pixels set, want 16", got)
+	}
+
+	// Scaled down by 3 with the threshold filter, the pixel on the right is
+	// covered by a third and isn't drawn.
+	part := koebiten.NewImage(6, 3)
+	koebiten.DrawFilledRect(part, 0, 0, 4, 3, pixel.NewMonochrome(0xFF, 0xFF, 0xFF))
+	dst = koebiten.NewImage(4, 4)
+	op = koebiten.DrawImageOptions{Filter: koebiten.FilterThreshold}
+	op.GeoM.Scale(1.0/3, 1.0/3)
+	part.DrawImage(dst, op)
+	if got := count(dst); got != 1 {
+		t.Errorf("threshold: %d pixels set, want 1", got)
+	}
+}
+
+func TestImageStdlibInterop(t *testing.T) {
+	src := image.NewRGBA(image.Rect(10, 10, 14, 12))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}), image.Point{}, draw.Src)
+	src.SetRGBA(11, 10, color.RGBA{A: 0xFF})
+
+	// Invert sets the light pixels, so the image keeps the colors of src.
+	img := koebiten.NewImageFromImage(src, &koebiten.NewImageFromImageOptions{Invert: true})
+	if got, want := img.Bounds(), image.Rect(0, 0, 4, 2); got != want {
+		t.Fatalf("Bounds() = %v, want %v", got, want)
+	}
+	if got := img.PixelAt(1, 0); got != (color.RGBA{A: 0xFF}) {
+		t.Errorf("black pixel = %v", got)
+	}
+	if got := img.PixelAt(0, 0); got != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Errorf("white pixel = %v", got)
+	}
+
+	// Encode a sub-image with image/png and decode it back.
+	sub := img.SubImage(image.Rect(1, 0, 3, 2))
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, sub); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dec.Bounds(); got != image.Rect(0, 0, 2, 2) {
+		t.Errorf("decoded bounds = %v", got)
+	}
+	if r, _, _, _ := dec.At(0, 0).RGBA(); r != 0 {
+		t.Errorf("decoded pixel (0, 0) is not black")
+	}
+	if r, _, _, _ := dec.At(1, 0).RGBA(); r != 0xFFFF {
+		t.Errorf("decoded pixel (1, 0) is not white")
+	}
+
+	// Draw into an RGB565 image with image/draw.
+	dst := koebiten.NewImageFromImage(src, &koebiten.NewImageFromImageOptions{Format: koebiten.FormatRGB565})
+	draw.Draw(dst, image.Rect(2, 0, 4, 2), image.NewUniform(color.RGBA{R: 0xFF, A: 0xFF}), image.Point{}, draw.Src)
+	if got := dst.At(3, 1); got != dst.ColorModel().Convert(color.RGBA{R: 0xFF, A: 0xFF}) {
+		t.Errorf("drawn pixel = %v", got)
+	}
+	if got := dst.At(4, 1); got != (color.RGBA{}) {
+		t.Errorf("At outside the bounds = %v, want transparent", got)
+	}
+}
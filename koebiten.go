@@ -1,19 +1,15 @@
-//go:build tinygo
-
 package koebiten
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image/color"
 	"io/fs"
 	"reflect"
-	"strings"
 	"time"
 
-	"github.com/chewxy/math32"
 	"tinygo.org/x/drivers"
-	"tinygo.org/x/drivers/image/png"
 	"tinygo.org/x/drivers/pixel"
 	"tinygo.org/x/tinydraw"
 	"tinygo.org/x/tinyfont"
@@ -27,11 +23,13 @@ type Displayer interface {
 }
 
 var (
+	// display is the Displayer that the game draws to.
 	display Displayer
+	// deviceDisplay is the Displayer of the hardware.
+	deviceDisplay Displayer
+	rotation      int
 
 	textY           int16
-	ticks           uint32
-	tickTimes       [32]uint32
 	enableBenchmark bool
 )
 
@@ -42,8 +40,18 @@ var (
 
 var keyUpdate = func() error { return nil }
 
-func init() {
-	pngBuffer = map[string]pixel.Image[pixel.Monochrome]{}
+// sleep pauses the main loop for d, or until done is closed. It reports
+// whether the whole duration has passed. It is a variable so that tests can
+// replace the clock.
+var sleep = func(d time.Duration, done <-chan struct{}) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-done:
+		return false
+	case <-t.C:
+		return true
+	}
 }
 
 // Run starts the main loop for the application.
@@ -51,32 +59,106 @@ func Run(d func()) error {
 	return RunGame(dummyGame(d))
 }
 
+// RunGame starts the main loop and runs the game.
+//
+// Layout of the game is called every frame with the size of the display.
+// If the logical screen size differs from it, the screen is scaled by an
+// integer factor and centered on the display.
+//
+// Update is called TPS() times per second on a fixed timestep. When the game
+// falls behind, Update is called again without waiting and Draw and Display
+// are skipped, up to MaxFrameSkip() frames in a row. If the game is still
+// behind after that, the frame is drawn and the remaining delay is dropped.
 func RunGame(game Game) error {
-	tick := time.Tick(32 * time.Millisecond)
+	return RunGameWithOptions(game, nil)
+}
+
+// RunGameOptions represents options for RunGameWithOptions.
+type RunGameOptions struct {
+	// Context stops the game when it is canceled. RunGameWithOptions then
+	// returns nil, like when Update returns Termination.
+	//
+	// The default (zero) value is nil, which means the game is never canceled.
+	Context context.Context
+
+	// MaxFrames is the number of ticks after which RunGameWithOptions returns
+	// nil. Frames are counted as Update calls, so the limit doesn't depend on
+	// the frames skipped to catch up. The last tick is always drawn.
+	//
+	// The default (zero) value is 0, which means there is no limit.
+	MaxFrames int
+
+	// TPS is set with SetTPS before the game starts.
+	//
+	// The default (zero) value is 0, which means the current TPS is kept.
+	TPS int
+
+	// Rotation points to the rotation mode set with SetRotation before the
+	// game starts.
+	//
+	// The default (zero) value is nil, which means the current rotation is
+	// kept.
+	Rotation *int
+}
+
+// RunGameWithOptions starts the main loop and runs the game with the specified options.
+// If options is nil, the default options are used.
+//
+// RunGameWithOptions returns when Update returns an error, when the context is
+// canceled or when MaxFrames ticks have run. It can be called again afterwards
+// to run another game.
+func RunGameWithOptions(game Game, options *RunGameOptions) error {
+	if options == nil {
+		options = &RunGameOptions{}
+	} else {
+		if options.TPS != 0 {
+			SetTPS(options.TPS)
+		}
+		if options.Rotation != nil {
+			SetRotation(*options.Rotation)
+		}
+	}
+	done := (<-chan struct{})(nil)
+	if options.Context != nil {
+		done = options.Context.Done()
+	}
+
+	next := now()
+	skipped := 0
+	first := true
+	frames := 0
 	for {
-		<-tick
-		ticks++
-		if enableBenchmark && (ticks%32) == 0 {
-			// print per 32 frame
-			min := uint32(0xFFFFFFFF)
-			max := uint32(0x00000000)
-			for _, t := range tickTimes {
-				if t < min {
-					min = t
-				}
-				if max < t {
-					max = t
-				}
-				fmt.Printf("%02d,", t/320)
+		d := theClock.tickDuration()
+		if d > 0 {
+			if wait := next.Sub(now()); wait > 0 && !sleep(wait, done) {
+				return nil
+			}
+		}
+		// The context may be canceled while waiting, so it is checked after
+		// the wait and before Update.
+		select {
+		case <-done:
+			return nil
+		default:
+		}
+		theClock.tick(now())
+		if enableBenchmark {
+			if ticks := theFrameStats.numTicks(); ticks > 0 && ticks%32 == 0 {
+				// print per 32 frame
+				printFrameStats()
 			}
-			fmt.Printf(" %3d %% - %3d %%\n", min/320, max/320)
 		}
-		s := time.Now().UnixMicro()
 
-		keyUpdate()
-		theInputState.update()
-		textY = 0
-		display.ClearBuffer()
+		if first {
+			// Layout is invoked before Update is called in the first frame.
+			updateLayout(game)
+			first = false
+		}
+
+		s := now()
+		updateInput()
+		checkScreenshotHotkey()
+		theConsole.update()
 		err := game.Update()
 		if err != nil {
 			if errors.Is(err, Termination) {
@@ -84,11 +166,36 @@ func RunGame(game Game) error {
 			}
 			return err
 		}
+		theFrameStats.addUpdate(now().Sub(s))
+		frames++
+		last := options.MaxFrames > 0 && frames >= options.MaxFrames
+
+		next = next.Add(d)
+		if d > 0 && !last && !now().Before(next) {
+			// Behind schedule: catch up by skipping this frame.
+			if skipped < theClock.maxFrameSkip() {
+				skipped++
+				theFrameStats.addSkip()
+				continue
+			}
+			next = now()
+		}
+		skipped = 0
+
+		updateLayout(game)
+		s = now()
+		textY = 0
+		display.ClearBuffer()
 		game.Draw(nil)
+		theConsole.draw(display)
+		drawn := now()
 		display.Display()
-		tickTimes[ticks%32] = uint32(time.Now().UnixMicro() - s)
+		theFrameStats.addFrame(drawn.Sub(s), now().Sub(drawn))
+		afterDisplay()
+		if last {
+			return nil
+		}
 	}
-	return nil
 }
 
 // SetWindowSize sets the size of the display window.
@@ -104,45 +211,52 @@ func SetHardware(h Hardware) error {
 	if err != nil {
 		return err
 	}
-	display = h.GetDisplay()
+	deviceDisplay = h.GetDisplay()
 	keyUpdate = h.KeyUpdate
+	setupDisplay()
 	return nil
 }
 
 // SetRotation sets the display rotation mode.
-// If the display is already a RotatedDisplay, it updates the mode.
-// Otherwise, it wraps the existing display in a new RotatedDisplay with the specified mode.
+// The display of the hardware is wrapped in a RotatedDisplay with the specified mode.
 func SetRotation(mode int) {
-	d, ok := display.(*RotatedDisplay)
-	if ok {
-		d.mode = mode
-	} else {
-		display = &RotatedDisplay{
-			Displayer: display,
-			mode:      mode,
+	rotation = mode
+	setupDisplay()
+}
+
+// setupDisplay builds the display the game draws to from the display of the
+// hardware, the rotation, the logical screen size and the screen capture.
+func setupDisplay() {
+	if isNil(deviceDisplay) {
+		return
+	}
+	d := rotatedDisplay()
+	if screenWidth > 0 && screenHeight > 0 {
+		if w, h := d.Size(); int(w) != screenWidth || int(h) != screenHeight {
+			d = newScaledDisplay(d, screenWidth, screenHeight)
 		}
 	}
+	if theCapture != nil {
+		theCapture.setDisplayer(d)
+		d = theCapture
+	}
+	display = d
 }
 
-// Println prints formatted output to the display.
+// Println formats its arguments as fmt.Println does and prints them on a new
+// line of the display. The lines are reset every frame, and the lines below
+// the screen are not drawn.
+//
+// If the console is enabled with SetConsoleEnabled, the line is added to the
+// console instead.
 func Println(args ...any) {
-	str := []string{}
-	for _, x := range args {
-		s, ok := x.(string)
-		if ok {
-			str = append(str, s)
-			continue
-		}
-
-		i, ok := x.(int)
-		if ok {
-			str = append(str, fmt.Sprintf("%d", i))
-			continue
-		}
-	}
+	printText(fmt.Sprintln(args...))
+}
 
-	textY += 8
-	tinyfont.WriteLine(display, &tinyfont.Org01, 2, textY, strings.Join(str, " "), white)
+// Printf formats its arguments as fmt.Printf does and prints them like
+// Println. A newline is not needed at the end of format.
+func Printf(format string, args ...any) {
+	printText(fmt.Sprintf(format, args...))
 }
 
 // DrawText draws text on the display.
@@ -212,16 +326,32 @@ func DrawFilledTriangle(dst Displayer, x0, y0, x1, y1, x2, y2 int, c pixel.BaseC
 	tinydraw.FilledTriangle(dst, int16(x0), int16(y0), int16(x1), int16(y1), int16(x2), int16(y2), c.RGBA())
 }
 
-var (
-	buffer    [3 * 8 * 8 * 4]uint16
-	pngBuffer map[string]pixel.Image[pixel.Monochrome]
-)
+// imageFSBudget is the default memory budget of the images drawn by
+// DrawImageFS, in bytes.
+const imageFSBudget = 16 * 1024
+
+// imageFSAssets caches the images drawn by DrawImageFS.
+var imageFSAssets = NewAssets(imageFSBudget)
+
+// ImageFSAssets returns the cache of the images drawn by DrawImageFS,
+// DrawImageFSWithOptions and DrawImageFSWithError. Its budget is 16 KiB by
+// default. Use SetBudget to change it and UnloadUnused to free the images.
+func ImageFSAssets() *Assets {
+	return imageFSAssets
+}
 
 type DrawImageFSOptions struct {
 	GeoM GeoM
+
+	// ImageOptions are the options to load the image as by
+	// NewImageFromFSWithOptions.
+	// The default (zero) value is nil, which means that the image is loaded
+	// as by NewImageFromFS.
+	ImageOptions *NewImageFromImageOptions
 }
 
 // DrawImageFS draws an image from the filesystem onto the display.
+// The image is not drawn if it can't be loaded.
 //
 // Deprecated: Use Image and Image.DrawImage instead.
 func DrawImageFS(dst Displayer, fsys fs.FS, path string, x, y int) {
@@ -231,77 +361,54 @@ func DrawImageFS(dst Displayer, fsys fs.FS, path string, x, y int) {
 }
 
 // DrawImageFSWithOptions draws an image from the filesystem onto the display with options.
+// The image is not drawn if it can't be loaded. Use DrawImageFSWithError to
+// get the error.
 //
 // Deprecated: Use Image and Image.DrawImage instead.
 func DrawImageFSWithOptions(dst Displayer, fsys fs.FS, path string, options DrawImageFSOptions) {
+	_ = DrawImageFSWithError(dst, fsys, path, options)
+}
+
+// DrawImageFSWithError draws an image from the filesystem onto the display
+// like DrawImageFSWithOptions, and returns the error if the image can't be
+// loaded.
+//
+// The images are cached in ImageFSAssets. An image that doesn't fit in its
+// budget is loaded again every time it is drawn.
+func DrawImageFSWithError(dst Displayer, fsys fs.FS, path string, options DrawImageFSOptions) error {
 	if isNil(dst) {
 		dst = display
 	}
-	img, ok := pngBuffer[path]
-	if !ok {
-		p, err := fsys.Open(path)
-		if err != nil {
-			return
-		}
-
-		png.SetCallback(buffer[:], func(data []uint16, x, y, w, h, width, height int16) {
-			if img.Len() == 0 {
-				img = pixel.NewImage[pixel.Monochrome](int(width), int(height))
-			}
-
-			for yy := int16(0); yy < h; yy++ {
-				for xx := int16(0); xx < w; xx++ {
-					c := C565toRGBA(data[yy*w+xx])
-					cnt := 0
-					if c.R < 0x80 {
-						cnt++
-					}
-					if c.G < 0x80 {
-						cnt++
-					}
-					if c.B < 0x80 {
-						cnt++
-					}
-					if cnt >= 2 {
-						img.Set(int(x+xx), int(y+yy), true)
-					}
-				}
-			}
-		})
-
-		_, err = png.Decode(p)
-		if err != nil {
-			return
+	img, err := imageFSAssets.loadUnreferenced(fsys, path, options.ImageOptions)
+	if errors.Is(err, ErrAssetBudgetExceeded) {
+		if options.ImageOptions != nil {
+			img, err = loadImageFromFSWithOptions(fsys, path, options.ImageOptions)
+		} else {
+			img, err = LoadImage(fsys, path)
 		}
-		pngBuffer[path] = img
 	}
-
-	geoM := options.GeoM
-	if !geoM.IsInvertible() {
-		return
+	if err != nil {
+		return err
 	}
+	img.DrawImage(dst, DrawImageOptions{GeoM: options.GeoM})
+	return nil
+}
 
-	w, h := img.Size()
-	if geoM.a_1 == 0 && geoM.b == 0 && geoM.c == 0 && geoM.d_1 == 0 {
-		tx, ty := geoM.Apply(0, 0)
-		ox, oy := int(math32.Round(tx)), int(math32.Round(ty))
-		for yy := 0; yy < h; yy++ {
-			for xx := 0; xx < w; xx++ {
-				if img.Get(xx, yy) == true {
-					dst.SetPixel(int16(xx+ox), int16(yy+oy), white)
-				}
-			}
-		}
-	} else {
-		for yy := 0; yy < h; yy++ {
-			for xx := 0; xx < w; xx++ {
-				if img.Get(xx, yy) == true {
-					xxf, yyf := geoM.Apply(float32(xx), float32(yy))
-					dst.SetPixel(int16(math32.Round(xxf)), int16(math32.Round(yyf)), white)
-				}
-			}
-		}
+// Screen returns the Displayer that the game draws to. It is nil until the
+// hardware is set with SetHardware.
+func Screen() Displayer {
+	return display
+}
+
+// DisplayerOrScreen returns dst, or Screen if dst is nil or a nil pointer,
+// such as the screen passed to Game.Draw. Packages that draw to a Displayer
+// use it to draw to the screen when they are given nil, like the drawing
+// functions of koebiten.
+func DisplayerOrScreen(dst Displayer) Displayer {
+	if isNil(dst) {
+		return display
 	}
+	return dst
 }
 
 func isNil(d Displayer) bool {
@@ -0,0 +1,268 @@
+package koebiten
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// fakeClock replaces the clock of the main loop with a virtual one. The
+// clock and the tick history are restored when the test ends.
+func fakeClock(t *testing.T) *time.Time {
+	current := time.Unix(0, 0)
+	origNow, origSleep := now, sleep
+	theClock.m.Lock()
+	origTimes, origN := theClock.times, theClock.n
+	theClock.m.Unlock()
+	now = func() time.Time { return current }
+	sleep = func(d time.Duration, done <-chan struct{}) bool {
+		current = current.Add(d)
+		select {
+		case <-done:
+			return false
+		default:
+			return true
+		}
+	}
+	t.Cleanup(func() {
+		now, sleep = origNow, origSleep
+		theClock.m.Lock()
+		theClock.times, theClock.n = origTimes, origN
+		theClock.m.Unlock()
+	})
+	return &current
+}
+
+// fakeDisplay replaces the displays with an image of the given size, and
+// restores them when the test ends.
+func fakeDisplay(t *testing.T, width, height int16) *Image {
+	origDisplay, origDevice := display, deviceDisplay
+	origWidth, origHeight := screenWidth, screenHeight
+	t.Cleanup(func() {
+		display, deviceDisplay = origDisplay, origDevice
+		screenWidth, screenHeight = origWidth, origHeight
+	})
+	img := NewImage(width, height)
+	display, deviceDisplay = img, nil
+	return img
+}
+
+type slowGame struct {
+	clock     *time.Time
+	drawCost  time.Duration
+	updates   int
+	draws     int
+	maxUpdate int
+}
+
+func (g *slowGame) Update() error {
+	g.updates++
+	if g.updates >= g.maxUpdate {
+		return Termination
+	}
+	return nil
+}
+
+func (g *slowGame) Draw(screen *Image) {
+	g.draws++
+	*g.clock = g.clock.Add(g.drawCost)
+}
+
+func (g *slowGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return 128, 64
+}
+
+func TestRunGameFrameSkip(t *testing.T) {
+	c := fakeClock(t)
+	fakeDisplay(t, 128, 64)
+	defer SetMaxFrameSkip(MaxFrameSkip())
+
+	// Draw takes about three ticks, so the loop has to skip frames to keep the TPS.
+	SetMaxFrameSkip(4)
+	start := *c
+	game := &slowGame{clock: c, drawCost: 50 * time.Millisecond, maxUpdate: 60}
+	if err := RunGame(game); err != nil {
+		t.Fatal(err)
+	}
+	if game.draws == 0 || game.draws >= game.updates {
+		t.Errorf("draws: got %d for %d updates", game.draws, game.updates)
+	}
+	if elapsed := c.Sub(start); elapsed > 1100*time.Millisecond {
+		t.Errorf("60 updates took %v, want about 1s", elapsed)
+	}
+
+	// Without frame skipping, the game slows down instead.
+	SetMaxFrameSkip(0)
+	start = *c
+	game = &slowGame{clock: c, drawCost: 50 * time.Millisecond, maxUpdate: 60}
+	if err := RunGame(game); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := game.draws, game.updates-1; g != e {
+		t.Errorf("draws: got %d want %d", g, e)
+	}
+	if elapsed := c.Sub(start); elapsed < 2*time.Second {
+		t.Errorf("60 updates took %v, want about 3s", elapsed)
+	}
+}
+
+func TestRunGameCancelWhileWaiting(t *testing.T) {
+	fakeClock(t)
+	fakeDisplay(t, 128, 64)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	game := &slowGame{clock: new(time.Time), maxUpdate: 60}
+	fakeSleep := sleep
+	sleep = func(d time.Duration, done <-chan struct{}) bool {
+		if game.updates == 2 {
+			// The context is canceled while the timer fires.
+			cancel()
+			fakeSleep(d, nil)
+			return true
+		}
+		return fakeSleep(d, done)
+	}
+	if err := RunGameWithOptions(game, &RunGameOptions{Context: ctx}); err != nil {
+		t.Fatal(err)
+	}
+	if game.updates != 2 {
+		t.Errorf("updates = %d, want 2", game.updates)
+	}
+}
+
+func TestConsole(t *testing.T) {
+	SetConsoleEnabled(true)
+	t.Cleanup(func() {
+		SetConsoleEnabled(false)
+		SetConsoleScrollback(64)
+		ClearConsole()
+	})
+
+	Println("score", 1.5, true, []int{1, 2})
+	Printf("%03d", 7)
+	ConsoleWriter().Write([]byte("log: a\nlog: "))
+	ConsoleWriter().Write([]byte("b\n"))
+	want := []string{"score 1.5 true [1 2]", "007", "log: a", "log: b"}
+	if len(theConsole.lines) != len(want) {
+		t.Fatalf("lines = %q, want %q", theConsole.lines, want)
+	}
+	for i := range want {
+		if theConsole.lines[i] != want[i] {
+			t.Errorf("lines = %q, want %q", theConsole.lines, want)
+		}
+	}
+
+	// The scrolled view stays on the same lines.
+	ScrollConsole(2)
+	Println("new")
+	if got := theConsole.scroll; got != 3 {
+		t.Errorf("scroll = %d, want 3", got)
+	}
+	ScrollConsole(-10)
+	if got := theConsole.scroll; got != 0 {
+		t.Errorf("scroll = %d, want 0", got)
+	}
+
+	SetConsoleScrollback(2)
+	if got := theConsole.lines; len(got) != 2 || got[0] != "log: b" {
+		t.Errorf("lines = %q, want the last 2 lines", got)
+	}
+}
+
+func TestConsoleDraw(t *testing.T) {
+	SetConsoleEnabled(true)
+	t.Cleanup(func() {
+		SetConsoleEnabled(false)
+		ClearConsole()
+	})
+
+	// Two lines only cover the bottom rows of the frame.
+	Println("a")
+	Println("b")
+	img := NewImage(32, 32)
+	img.Fill(white)
+	theConsole.draw(img)
+	if got := img.PixelAt(31, 32-2*consoleLineHeight-1); got != white {
+		t.Errorf("pixel above the console = %v, want white", got)
+	}
+	if got := img.PixelAt(31, 32-2*consoleLineHeight); got != black {
+		t.Errorf("pixel of the console = %v, want black", got)
+	}
+}
+
+func TestConsoleWriterConcurrent(t *testing.T) {
+	SetConsoleEnabled(true)
+	t.Cleanup(func() {
+		SetConsoleEnabled(false)
+		ClearConsole()
+	})
+
+	w := ConsoleWriter()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 8 {
+				w.Write([]byte("line\n"))
+			}
+		}()
+	}
+	img := NewImage(32, 32)
+	for range 8 {
+		theConsole.draw(img)
+	}
+	wg.Wait()
+	if got := len(theConsole.lines); got != 32 {
+		t.Errorf("%d lines, want 32", got)
+	}
+}
+
+func TestDecodePNGCallbackPanic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"a.png": &fstest.MapFile{Data: buf.Bytes()}}
+
+	// A panic of a callback is not an error of the image.
+	defer func() {
+		if r := recover(); r != "set" {
+			t.Errorf("recover() = %v, want the panic of set", r)
+		}
+	}()
+	err := decodePNG(fsys, "a.png", func(width, height int) {}, func(x, y int, c color.RGBA) {
+		panic("set")
+	})
+	t.Errorf("decodePNG returned %v, want a panic", err)
+}
+
+func TestAssetsThresholdKey(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"a.png": &fstest.MapFile{Data: buf.Bytes()}}
+
+	// The same threshold behind different pointers shares the image.
+	a := NewAssets(0)
+	t1, t2 := uint8(0x40), uint8(0x40)
+	img1, err := a.loadUnreferenced(fsys, "a.png", &NewImageFromImageOptions{Threshold: &t1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img2, err := a.loadUnreferenced(fsys, "a.png", &NewImageFromImageOptions{Threshold: &t2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img1 != img2 || a.Len() != 1 {
+		t.Errorf("images with the same threshold are not shared: %d images", a.Len())
+	}
+}
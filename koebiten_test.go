@@ -0,0 +1,263 @@
+package koebiten_test
+
+import (
+	"bytes"
+	"context"
+	"image/gif"
+	"testing"
+
+	"github.com/sago35/koebiten"
+	"github.com/sago35/koebiten/hardware"
+	"tinygo.org/x/drivers/pixel"
+)
+
+type testGame struct {
+	updates int
+	max     int
+	keys    []int
+}
+
+func (g *testGame) Update() error {
+	g.updates++
+	g.keys = append(g.keys, koebiten.KeyPressDuration(koebiten.Key0))
+	if g.updates >= g.max {
+		return koebiten.Termination
+	}
+	return nil
+}
+
+func (g *testGame) Draw(screen *koebiten.Image) {
+	koebiten.DrawFilledRect(nil, 10, 20, 4, 4, pixel.NewMonochrome(0xFF, 0xFF, 0xFF))
+}
+
+func (g *testGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return 128, 64
+}
+
+func TestRunGameHeadless(t *testing.T) {
+	dev := hardware.NewHeadless(128, 64)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+	dev.Press(koebiten.Key0)
+
+	game := &testGame{max: 3}
+	if err := koebiten.RunGame(game); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, e := game.updates, 3; g != e {
+		t.Errorf("updates: got %d want %d", g, e)
+	}
+	for i, d := range game.keys {
+		if g, e := d, i+1; g != e {
+			t.Errorf("key duration at tick %d: got %d want %d", i, g, e)
+		}
+	}
+
+	img := dev.Display().Image()
+	if g, e := img.RGBAAt(11, 21).R, uint8(0xFF); g != e {
+		t.Errorf("pixel inside rect: got %02X want %02X", g, e)
+	}
+	if g, e := img.RGBAAt(0, 0).R, uint8(0x00); g != e {
+		t.Errorf("pixel outside rect: got %02X want %02X", g, e)
+	}
+}
+
+func TestScreenCapture(t *testing.T) {
+	dev := hardware.NewHeadless(128, 64)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+	koebiten.SetScreenCaptureEnabled(true)
+	defer koebiten.SetScreenCaptureEnabled(false)
+
+	var buf bytes.Buffer
+	rec := koebiten.RecordGIF(&buf, 2)
+	if err := koebiten.RunGame(&testGame{max: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if !rec.Done() || rec.Err() != nil {
+		t.Fatalf("recording: done %v, err %v", rec.Done(), rec.Err())
+	}
+	g, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(g.Image), 2; got != want {
+		t.Errorf("frames: got %d want %d", got, want)
+	}
+	if r, _, _, _ := g.Image[1].At(11, 21).RGBA(); r != 0xFFFF {
+		t.Errorf("pixel inside rect: got %04X want FFFF", r)
+	}
+
+	img, err := koebiten.Screenshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := img.RGBAAt(11, 21), dev.Display().Image().RGBAAt(11, 21); got != want {
+		t.Errorf("screenshot: got %v want %v", got, want)
+	}
+}
+
+func TestRunGameLayout(t *testing.T) {
+	// The 128x64 screen is scaled by 2 and centered on a 300x140 display.
+	dev := hardware.NewHeadless(300, 140)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+	if err := koebiten.RunGame(&testGame{max: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	img := dev.Display().Image()
+	ox, oy := 22, 6
+	tests := []struct {
+		x, y int
+		want uint8
+	}{
+		{ox + 20, oy + 40, 0xFF},
+		{ox + 27, oy + 47, 0xFF},
+		{ox + 19, oy + 40, 0x00},
+		{ox + 28, oy + 47, 0x00},
+		{10, 20, 0x00},
+	}
+	for _, tt := range tests {
+		if g := img.RGBAAt(tt.x, tt.y).R; g != tt.want {
+			t.Errorf("(%d, %d): got %02X want %02X", tt.x, tt.y, g, tt.want)
+		}
+	}
+}
+
+func TestRunGameWithOptions(t *testing.T) {
+	dev := hardware.NewHeadless(128, 64)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+	defer koebiten.SetTPS(koebiten.TPS())
+
+	game := &testGame{max: 1000}
+	frames := dev.Display().Frames()
+	err := koebiten.RunGameWithOptions(game, &koebiten.RunGameOptions{
+		MaxFrames: 5,
+		TPS:       koebiten.SyncWithFPS,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, e := game.updates, 5; g != e {
+		t.Errorf("updates: got %d want %d", g, e)
+	}
+	if g, e := dev.Display().Frames()-frames, 5; g != e {
+		t.Errorf("frames: got %d want %d", g, e)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	game = &testGame{max: 1000}
+	cancel()
+	if err := koebiten.RunGameWithOptions(game, &koebiten.RunGameOptions{Context: ctx}); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := game.updates, 0; g != e {
+		t.Errorf("updates after cancel: got %d want %d", g, e)
+	}
+}
+
+// layoutGame records the size of the display passed to Layout.
+type layoutGame struct {
+	testGame
+	outsideWidth  int
+	outsideHeight int
+}
+
+func (g *layoutGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	g.outsideWidth, g.outsideHeight = outsideWidth, outsideHeight
+	return outsideWidth, outsideHeight
+}
+
+func TestRunGameWithOptionsKeepsRotation(t *testing.T) {
+	dev := hardware.NewHeadless(128, 64)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+	koebiten.SetRotation(koebiten.Rotation90)
+	defer koebiten.SetRotation(koebiten.Rotation0)
+
+	game := &layoutGame{testGame: testGame{max: 1000}}
+	if err := koebiten.RunGameWithOptions(game, &koebiten.RunGameOptions{MaxFrames: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if game.outsideWidth != 64 || game.outsideHeight != 128 {
+		t.Errorf("Layout got %dx%d, want 64x128", game.outsideWidth, game.outsideHeight)
+	}
+
+	rotation := koebiten.Rotation0
+	if err := koebiten.RunGameWithOptions(game, &koebiten.RunGameOptions{MaxFrames: 1, Rotation: &rotation}); err != nil {
+		t.Fatal(err)
+	}
+	if game.outsideWidth != 128 || game.outsideHeight != 64 {
+		t.Errorf("Layout with Rotation0 got %dx%d, want 128x64", game.outsideWidth, game.outsideHeight)
+	}
+}
+
+type printGame struct {
+	updates int
+}
+
+func (g *printGame) Update() error {
+	g.updates++
+	koebiten.Println("tick", g.updates)
+	if g.updates >= 3 {
+		return koebiten.Termination
+	}
+	return nil
+}
+
+func (g *printGame) Draw(screen *koebiten.Image) {
+	koebiten.DrawFilledRect(nil, 0, 0, 128, 64, pixel.NewMonochrome(0xFF, 0xFF, 0xFF))
+}
+
+func (g *printGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return 128, 64
+}
+
+func TestConsoleToggle(t *testing.T) {
+	dev := hardware.NewHeadless(128, 64)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+	koebiten.SetConsoleEnabled(true)
+	koebiten.SetConsoleVisible(false)
+	koebiten.SetConsoleToggleKey(koebiten.Key0)
+	t.Cleanup(func() {
+		koebiten.SetConsoleEnabled(false)
+		koebiten.SetConsoleVisible(true)
+		koebiten.SetConsoleToggleKey()
+		koebiten.ClearConsole()
+	})
+
+	// The console is shown over the bottom of the screen of the game.
+	dev.Press(koebiten.Key0)
+	if err := koebiten.RunGame(&printGame{}); err != nil {
+		t.Fatal(err)
+	}
+	if !koebiten.IsConsoleVisible() {
+		t.Fatal("IsConsoleVisible() = false after the toggle key")
+	}
+	img := dev.Display().Image()
+	if g := img.RGBAAt(127, 63).R; g != 0x00 {
+		t.Errorf("pixel under the console: got %02X want 00", g)
+	}
+	if g := img.RGBAAt(127, 0).R; g != 0xFF {
+		t.Errorf("pixel above the console: got %02X want FF", g)
+	}
+	lit := false
+	for x := 0; x < 64; x++ {
+		for y := 40; y < 64; y++ {
+			lit = lit || img.RGBAAt(x, y).R != 0
+		}
+	}
+	if !lit {
+		t.Errorf("no text in the console")
+	}
+}
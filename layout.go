@@ -0,0 +1,99 @@
+package koebiten
+
+import (
+	"image/color"
+)
+
+var (
+	// screenWidth and screenHeight are the logical screen size returned by Game.Layout.
+	// They are 0 until Layout is called.
+	screenWidth  int
+	screenHeight int
+)
+
+// updateLayout calls Layout of the game with the size of the display and
+// updates the screen if the logical size has changed.
+func updateLayout(game Game) {
+	if isNil(deviceDisplay) {
+		return
+	}
+	ow, oh := deviceDisplay.Size()
+	if rotation == Rotation90 || rotation == Rotation270 {
+		ow, oh = oh, ow
+	}
+	w, h := game.Layout(int(ow), int(oh))
+	if w <= 0 || h <= 0 {
+		panic("koebiten: Layout must return positive numbers")
+	}
+	if w == screenWidth && h == screenHeight {
+		return
+	}
+	screenWidth, screenHeight = w, h
+	setupDisplay()
+}
+
+// rotatedDisplay returns the display of the hardware with the rotation applied.
+func rotatedDisplay() Displayer {
+	if rotation == Rotation0 {
+		return deviceDisplay
+	}
+	return &RotatedDisplay{
+		Displayer: deviceDisplay,
+		mode:      rotation,
+	}
+}
+
+// scaledDisplay draws a logical screen onto a larger Displayer.
+// The screen is scaled by the largest integer that fits and centered, and the
+// rest of the Displayer is left blank.
+type scaledDisplay struct {
+	Displayer
+	w     int16
+	h     int16
+	scale int16
+	ox    int16
+	oy    int16
+}
+
+func newScaledDisplay(d Displayer, w, h int) *scaledDisplay {
+	dw, dh := d.Size()
+	scale := min(int(dw)/w, int(dh)/h)
+	if scale < 1 {
+		// The screen is larger than the display: crop it around the center.
+		scale = 1
+	}
+	return &scaledDisplay{
+		Displayer: d,
+		w:         int16(w),
+		h:         int16(h),
+		scale:     int16(scale),
+		ox:        int16((int(dw) - w*scale) / 2),
+		oy:        int16((int(dh) - h*scale) / 2),
+	}
+}
+
+func (d *scaledDisplay) Size() (x, y int16) {
+	return d.w, d.h
+}
+
+func (d *scaledDisplay) SetPixel(x, y int16, c color.RGBA) {
+	if x < 0 || d.w <= x || y < 0 || d.h <= y {
+		return
+	}
+	px := d.ox + x*d.scale
+	py := d.oy + y*d.scale
+	for yy := int16(0); yy < d.scale; yy++ {
+		for xx := int16(0); xx < d.scale; xx++ {
+			d.Displayer.SetPixel(px+xx, py+yy, c)
+		}
+	}
+}
+
+// PixelAt returns the color of the top-left display pixel of the screen pixel.
+func (d *scaledDisplay) PixelAt(x, y int16) color.RGBA {
+	r, ok := d.Displayer.(PixelReader)
+	if !ok || x < 0 || d.w <= x || y < 0 || d.h <= y {
+		return black
+	}
+	return r.PixelAt(d.ox+x*d.scale, d.oy+y*d.scale)
+}
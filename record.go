@@ -0,0 +1,189 @@
+package koebiten
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The input record format starts with inputRecordMagic, the format version
+// and the TPS as an uvarint. It is followed by runs of ticks, each stored as
+// two uvarints: the number of ticks and a bit mask of the pressed keys.
+const (
+	inputRecordMagic   = "KBIR"
+	inputRecordVersion = 1
+)
+
+// ErrInvalidInputRecord is returned by ReplayInput when the data is not an input record.
+var ErrInvalidInputRecord = errors.New("koebiten: invalid input record")
+
+var (
+	theInputRecorder *inputRecorder
+	theInputReplayer *inputReplayer
+)
+
+type inputRecorder struct {
+	w     *bufio.Writer
+	mask  uint32
+	count uint64
+	err   error
+}
+
+type inputReplayer struct {
+	r     *bufio.Reader
+	mask  uint32
+	count uint64
+}
+
+// RecordInput starts recording the key state of every tick to w.
+// The data is compact: a run of ticks with the same key state takes a few bytes.
+// StopRecordingInput must be called to write the remaining data.
+//
+// RecordInput should be called before RunGame or in Update.
+func RecordInput(w io.Writer) error {
+	if theInputRecorder != nil {
+		return errors.New("koebiten: input is already being recorded")
+	}
+	bw := bufio.NewWriter(w)
+	var buf [binary.MaxVarintLen64]byte
+	bw.WriteString(inputRecordMagic)
+	bw.WriteByte(inputRecordVersion)
+	bw.Write(buf[:binary.PutUvarint(buf[:], uint64(TPS()))])
+	theInputRecorder = &inputRecorder{w: bw}
+	return nil
+}
+
+// StopRecordingInput stops the recording started by RecordInput and flushes the data.
+// It returns the first error that occurred while writing.
+func StopRecordingInput() error {
+	r := theInputRecorder
+	if r == nil {
+		return nil
+	}
+	theInputRecorder = nil
+	r.flushRun()
+	if r.err != nil {
+		return r.err
+	}
+	return r.w.Flush()
+}
+
+// ReplayInput makes RunGame read the key state of every tick from r instead
+// of calling Hardware.KeyUpdate. The TPS is set to the value the input was
+// recorded with. When the record ends, the keys are read from the hardware
+// again.
+//
+// For a replay to reproduce a session tick for tick, the game must not depend
+// on anything else that changes between runs, such as the wall clock or an
+// unseeded random number generator.
+func ReplayInput(r io.Reader) error {
+	br := bufio.NewReader(r)
+	var header [len(inputRecordMagic) + 1]byte
+	if _, err := io.ReadFull(br, header[:]); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInputRecord, err)
+	}
+	if string(header[:len(inputRecordMagic)]) != inputRecordMagic {
+		return ErrInvalidInputRecord
+	}
+	if v := header[len(inputRecordMagic)]; v != inputRecordVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidInputRecord, v)
+	}
+	tps, err := binary.ReadUvarint(br)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidInputRecord, err)
+	}
+	if tps > 0 {
+		SetTPS(int(tps))
+	}
+	theInputReplayer = &inputReplayer{r: br}
+	return nil
+}
+
+// IsReplayingInput reports whether the key state is currently read from an input record.
+func IsReplayingInput() bool {
+	return theInputReplayer != nil
+}
+
+// updateInput updates the key state for a tick, from the input record or from
+// the hardware, and records it if needed.
+func updateInput() {
+	if theInputReplayer != nil {
+		if mask, ok := theInputReplayer.next(); ok {
+			setKeyMask(mask)
+		} else {
+			theInputReplayer = nil
+			keyUpdate()
+		}
+	} else {
+		keyUpdate()
+	}
+	if theInputRecorder != nil {
+		theInputRecorder.add(keyMask())
+	}
+	theInputState.update()
+}
+
+func keyMask() uint32 {
+	theInputState.m.RLock()
+	defer theInputState.m.RUnlock()
+	mask := uint32(0)
+	for k, pressed := range theInputState.state {
+		if pressed {
+			mask |= 1 << k
+		}
+	}
+	return mask
+}
+
+func setKeyMask(mask uint32) {
+	theInputState.m.Lock()
+	defer theInputState.m.Unlock()
+	for k := range theInputState.state {
+		theInputState.state[k] = mask&(1<<k) != 0
+	}
+}
+
+func (r *inputRecorder) add(mask uint32) {
+	if r.count > 0 && mask != r.mask {
+		r.flushRun()
+	}
+	r.mask = mask
+	r.count++
+}
+
+func (r *inputRecorder) flushRun() {
+	if r.count == 0 || r.err != nil {
+		return
+	}
+	var buf [binary.MaxVarintLen64]byte
+	if _, err := r.w.Write(buf[:binary.PutUvarint(buf[:], r.count)]); err != nil {
+		r.err = err
+		return
+	}
+	if _, err := r.w.Write(buf[:binary.PutUvarint(buf[:], uint64(r.mask))]); err != nil {
+		r.err = err
+		return
+	}
+	r.count = 0
+}
+
+// next returns the key state of the next tick.
+// It returns false at the end of the record.
+func (r *inputReplayer) next() (uint32, bool) {
+	for r.count == 0 {
+		count, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return 0, false
+		}
+		mask, err := binary.ReadUvarint(r.r)
+		if err != nil {
+			return 0, false
+		}
+		r.count = count
+		r.mask = uint32(mask)
+	}
+	r.count--
+	return r.mask, true
+}
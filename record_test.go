@@ -0,0 +1,72 @@
+package koebiten
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestRecordAndReplayInput(t *testing.T) {
+	defer SetTPS(TPS())
+	SetTPS(30)
+
+	// A session where Key0 is held for 3 ticks and KeyUp for 2 ticks later.
+	session := [][]Key{{}, {Key0}, {Key0}, {Key0}, {}, {KeyUp}, {KeyUp, Key1}, {}}
+	tick := 0
+	origKeyUpdate := keyUpdate
+	defer func() { keyUpdate = origKeyUpdate }()
+	keyUpdate = func() error {
+		setKeyMask(0)
+		AppendPressedKeys(session[tick])
+		return nil
+	}
+
+	var rec bytes.Buffer
+	if err := RecordInput(&rec); err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{}
+	for tick = range session {
+		updateInput()
+		want = append(want, keyMask())
+	}
+	if err := StopRecordingInput(); err != nil {
+		t.Fatal(err)
+	}
+
+	SetTPS(60)
+	keyUpdate = func() error {
+		t.Errorf("keyUpdate must not be called while replaying")
+		return nil
+	}
+	if err := ReplayInput(bytes.NewReader(rec.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if g, e := TPS(), 30; g != e {
+		t.Errorf("TPS: got %d want %d", g, e)
+	}
+	for i, e := range want {
+		updateInput()
+		if g := keyMask(); g != e {
+			t.Errorf("tick %d: got %05X want %05X", i, g, e)
+		}
+	}
+	if !IsKeyJustReleased(KeyUp) {
+		t.Errorf("KeyUp must be just released at the end of the session")
+	}
+
+	// The hardware takes over at the end of the record.
+	called := false
+	keyUpdate = func() error {
+		called = true
+		return nil
+	}
+	updateInput()
+	if !called || IsReplayingInput() {
+		t.Errorf("the replay did not end")
+	}
+
+	if err := ReplayInput(bytes.NewReader([]byte("PNG"))); !errors.Is(err, ErrInvalidInputRecord) {
+		t.Errorf("got %v want %v", err, ErrInvalidInputRecord)
+	}
+}
@@ -0,0 +1,281 @@
+package koebiten
+
+import (
+	"tinygo.org/x/drivers/pixel"
+)
+
+// Scene is a part of a game, such as a title screen, a stage or a pause menu.
+//
+// Scenes are managed by a SceneManager.
+type Scene interface {
+	// Update updates the scene by one tick. It is called only while the scene is
+	// on top of the SceneManager and no transition is running.
+	Update() error
+
+	// Draw draws the scene.
+	Draw(screen *Image)
+
+	// OnEnter is called when the scene becomes the top scene.
+	OnEnter()
+
+	// OnExit is called when the scene stops being the top scene, either because
+	// it is removed or because another scene is pushed over it.
+	OnExit()
+}
+
+// Transition draws the change from one scene to another.
+type Transition interface {
+	// Draw draws the transition. from is the previous top scene and to is the
+	// new one. progress goes from 0 at the start to 1 at the end.
+	Draw(screen *Image, from, to Scene, progress float32)
+}
+
+// TransitionFunc is a function that implements Transition.
+type TransitionFunc func(screen *Image, from, to Scene, progress float32)
+
+// Draw calls f(screen, from, to, progress).
+func (f TransitionFunc) Draw(screen *Image, from, to Scene, progress float32) {
+	f(screen, from, to, progress)
+}
+
+var _ Game = (*SceneManager)(nil)
+
+// SceneManager is a stack of scenes. It implements Game, so it can be passed
+// to RunGame directly.
+//
+// Scene changes requested with Push, Pop or Replace during Update are applied
+// after the current scene's Update returns. Changes requested from OnEnter or
+// OnExit are applied right after the change being applied.
+type SceneManager struct {
+	stack   []Scene
+	changes []sceneChange
+	started bool
+
+	transition Transition
+	duration   int
+
+	// The running transition.
+	from    Scene
+	to      Scene
+	running Transition
+	tick    int
+	length  int
+}
+
+type sceneOp int
+
+const (
+	scenePush sceneOp = iota
+	scenePop
+	sceneReplace
+)
+
+type sceneChange struct {
+	op    sceneOp
+	scene Scene
+}
+
+// NewSceneManager creates a SceneManager whose first scene is first.
+func NewSceneManager(first Scene) *SceneManager {
+	return &SceneManager{
+		stack: []Scene{first},
+	}
+}
+
+// SetTransition sets the transition used by the following scene changes.
+// duration is the length of the transition in ticks.
+// If t is nil or duration is not positive, scenes change immediately.
+func (m *SceneManager) SetTransition(t Transition, duration int) {
+	m.transition = t
+	m.duration = duration
+}
+
+// Push pushes s on top of the current scene.
+func (m *SceneManager) Push(s Scene) {
+	m.changes = append(m.changes, sceneChange{op: scenePush, scene: s})
+}
+
+// Pop removes the top scene and goes back to the one below it.
+// If it was the last scene, Update returns Termination.
+func (m *SceneManager) Pop() {
+	m.changes = append(m.changes, sceneChange{op: scenePop})
+}
+
+// Replace replaces the top scene with s.
+func (m *SceneManager) Replace(s Scene) {
+	m.changes = append(m.changes, sceneChange{op: sceneReplace, scene: s})
+}
+
+// Current returns the top scene, or nil if there are no scenes.
+func (m *SceneManager) Current() Scene {
+	if len(m.stack) == 0 {
+		return nil
+	}
+	return m.stack[len(m.stack)-1]
+}
+
+// Len returns the number of scenes in the stack.
+func (m *SceneManager) Len() int {
+	return len(m.stack)
+}
+
+// IsTransitioning reports whether a transition is running.
+func (m *SceneManager) IsTransitioning() bool {
+	return m.running != nil
+}
+
+// Update updates the top scene, or advances the running transition.
+//
+// It implements the Game interface.
+func (m *SceneManager) Update() error {
+	if !m.started {
+		m.started = true
+		if s := m.Current(); s != nil {
+			s.OnEnter()
+		}
+		// The first scene may change the scene as soon as it is entered.
+		m.applyChanges()
+		if len(m.stack) == 0 {
+			return Termination
+		}
+		if m.running != nil {
+			return nil
+		}
+	}
+
+	if m.running != nil {
+		m.tick++
+		if m.tick >= m.length {
+			m.running = nil
+			m.from = nil
+			m.to = nil
+		}
+		return nil
+	}
+
+	if s := m.Current(); s != nil {
+		if err := s.Update(); err != nil {
+			return err
+		}
+	}
+	m.applyChanges()
+	if len(m.stack) == 0 {
+		return Termination
+	}
+	return nil
+}
+
+// Draw draws the top scene, or the running transition.
+//
+// It implements the Game interface.
+func (m *SceneManager) Draw(screen *Image) {
+	if m.running != nil {
+		m.running.Draw(screen, m.from, m.to, float32(m.tick)/float32(m.length))
+		return
+	}
+	if s := m.Current(); s != nil {
+		s.Draw(screen)
+	}
+}
+
+// Layout returns the layout of the top scene if it has a Layout method.
+// Otherwise the screen has the size of the display.
+//
+// It implements the Game interface.
+func (m *SceneManager) Layout(outsideWidth, outsideHeight int) (int, int) {
+	if l, ok := m.Current().(interface {
+		Layout(outsideWidth, outsideHeight int) (int, int)
+	}); ok {
+		return l.Layout(outsideWidth, outsideHeight)
+	}
+	return outsideWidth, outsideHeight
+}
+
+// applyChanges applies the queued scene changes in order, including the ones
+// queued by OnEnter and OnExit while they are applied.
+func (m *SceneManager) applyChanges() {
+	if len(m.changes) == 0 {
+		return
+	}
+	from := m.Current()
+	for len(m.changes) > 0 {
+		changes := m.changes
+		m.changes = nil
+		for _, c := range changes {
+			if top := m.Current(); top != nil {
+				top.OnExit()
+			}
+			switch c.op {
+			case scenePush:
+				m.stack = append(m.stack, c.scene)
+			case scenePop:
+				if len(m.stack) > 0 {
+					m.stack = m.stack[:len(m.stack)-1]
+				}
+			case sceneReplace:
+				if len(m.stack) > 0 {
+					m.stack[len(m.stack)-1] = c.scene
+				} else {
+					m.stack = append(m.stack, c.scene)
+				}
+			}
+			if top := m.Current(); top != nil {
+				top.OnEnter()
+			}
+		}
+	}
+
+	to := m.Current()
+	if m.transition != nil && m.duration > 0 && from != nil && to != nil {
+		m.from = from
+		m.to = to
+		m.running = m.transition
+		m.tick = 0
+		m.length = m.duration
+	}
+}
+
+// FadeTransition fades the previous scene out to black, then fades the new
+// scene in. The fade is dithered, so it also works on monochrome displays.
+var FadeTransition Transition = TransitionFunc(func(screen *Image, from, to Scene, progress float32) {
+	// Black coverage goes up to 1 at the middle of the transition and back down.
+	coverage := progress * 2
+	if progress < 0.5 {
+		from.Draw(screen)
+	} else {
+		to.Draw(screen)
+		coverage = 2 - coverage
+	}
+	dst := Displayer(screen)
+	if isNil(dst) {
+		dst = display
+	}
+	level := int(coverage * 16)
+	w, h := dst.Size()
+	for y := int16(0); y < h; y++ {
+		for x := int16(0); x < w; x++ {
+			if int(bayer4x4.at(int(x), int(y))) < level {
+				dst.SetPixel(x, y, black)
+			}
+		}
+	}
+})
+
+// WipeTransition covers the previous scene with black from left to right,
+// then uncovers the new scene from left to right.
+var WipeTransition Transition = TransitionFunc(func(screen *Image, from, to Scene, progress float32) {
+	dst := Displayer(screen)
+	if isNil(dst) {
+		dst = display
+	}
+	w, h := dst.Size()
+	c := pixel.NewMonochrome(0x00, 0x00, 0x00)
+	if progress < 0.5 {
+		from.Draw(screen)
+		DrawFilledRect(screen, 0, 0, int(float32(w)*progress*2), int(h), c)
+	} else {
+		to.Draw(screen)
+		x := int(float32(w) * (progress*2 - 1))
+		DrawFilledRect(screen, x, 0, int(w)-x, int(h), c)
+	}
+})
@@ -0,0 +1,146 @@
+package koebiten
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testScene struct {
+	name   string
+	log    *[]string
+	update func() error
+	enter  func()
+}
+
+func (s *testScene) Update() error {
+	*s.log = append(*s.log, s.name+".Update")
+	if s.update != nil {
+		return s.update()
+	}
+	return nil
+}
+
+func (s *testScene) Draw(screen *Image) {
+	*s.log = append(*s.log, s.name+".Draw")
+}
+
+func (s *testScene) OnEnter() {
+	*s.log = append(*s.log, s.name+".OnEnter")
+	if s.enter != nil {
+		s.enter()
+	}
+}
+
+func (s *testScene) OnExit() {
+	*s.log = append(*s.log, s.name+".OnExit")
+}
+
+func TestSceneManager(t *testing.T) {
+	var log []string
+	title := &testScene{name: "title", log: &log}
+	stage := &testScene{name: "stage", log: &log}
+	pause := &testScene{name: "pause", log: &log}
+	m := NewSceneManager(title)
+
+	title.update = func() error {
+		m.Replace(stage)
+		return nil
+	}
+	stage.update = func() error {
+		m.Push(pause)
+		return nil
+	}
+	pause.update = func() error {
+		m.Pop()
+		return nil
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := m.Update(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.Draw(nil)
+
+	want := []string{
+		"title.OnEnter", "title.Update", "title.OnExit", "stage.OnEnter",
+		"stage.Update", "stage.OnExit", "pause.OnEnter",
+		"pause.Update", "pause.OnExit", "stage.OnEnter",
+		"stage.Draw",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("got %v\nwant %v", log, want)
+	}
+	if g, e := m.Len(), 1; g != e {
+		t.Errorf("Len: got %d want %d", g, e)
+	}
+
+	stage.update = func() error {
+		m.Pop()
+		return nil
+	}
+	if err := m.Update(); !errors.Is(err, Termination) {
+		t.Errorf("got %v want %v", err, Termination)
+	}
+}
+
+func TestSceneManagerChangeOnEnter(t *testing.T) {
+	var log []string
+	splash := &testScene{name: "splash", log: &log}
+	title := &testScene{name: "title", log: &log}
+	stage := &testScene{name: "stage", log: &log}
+	m := NewSceneManager(splash)
+
+	// The scenes replace themselves as soon as they are entered.
+	splash.enter = func() { m.Replace(title) }
+	title.enter = func() { m.Replace(stage) }
+	if err := m.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"splash.OnEnter", "splash.OnExit", "title.OnEnter",
+		"title.OnExit", "stage.OnEnter", "stage.Update",
+	}
+	if !reflect.DeepEqual(log, want) {
+		t.Errorf("got %v\nwant %v", log, want)
+	}
+	if m.Current() != stage || m.Len() != 1 {
+		t.Errorf("Current: got %v (%d scenes) want stage", m.Current(), m.Len())
+	}
+}
+
+func TestSceneManagerTransition(t *testing.T) {
+	var log []string
+	a := &testScene{name: "a", log: &log}
+	b := &testScene{name: "b", log: &log}
+	m := NewSceneManager(a)
+
+	var progress []float32
+	m.SetTransition(TransitionFunc(func(screen *Image, from, to Scene, p float32) {
+		if from != a || to != b {
+			t.Errorf("unexpected scenes: %v -> %v", from, to)
+		}
+		progress = append(progress, p)
+	}), 4)
+
+	a.update = func() error {
+		m.Replace(b)
+		return nil
+	}
+	m.Update()
+	for m.IsTransitioning() {
+		m.Draw(nil)
+		m.Update()
+	}
+	if want := []float32{0, 0.25, 0.5, 0.75}; !reflect.DeepEqual(progress, want) {
+		t.Errorf("got %v want %v", progress, want)
+	}
+
+	log = log[:0]
+	m.Update()
+	if want := []string{"b.Update"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("got %v want %v", log, want)
+	}
+}
@@ -0,0 +1,373 @@
+package koebiten
+
+import (
+	"image"
+	"image/color"
+	"slices"
+
+	"github.com/chewxy/math32"
+	"tinygo.org/x/drivers/pixel"
+	"tinygo.org/x/tinydraw"
+)
+
+// DrawEllipse draws an ellipse on the display. (x, y) is the center, and rx
+// and ry are the radii.
+func DrawEllipse(dst Displayer, x, y, rx, ry int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	drawSpansOutline(dst, y-ry, ellipseSpans(x, rx, ry), c.RGBA())
+}
+
+// DrawFilledEllipse draws a filled ellipse on the display. (x, y) is the
+// center, and rx and ry are the radii.
+func DrawFilledEllipse(dst Displayer, x, y, rx, ry int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	drawSpans(dst, y-ry, ellipseSpans(x, rx, ry), c.RGBA())
+}
+
+// DrawArc draws an arc of the circle of radius r centered at (x, y) on the
+// display. The arc goes clockwise from the angle start to the angle end, in
+// radian. The angle 0 points to the right and the angle Pi/2 points down.
+func DrawArc(dst Displayer, x, y, r int, start, end float32, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	arc := &arcDisplay{Displayer: dst, cx: x, cy: y, start: start, end: end}
+	drawSpansOutline(arc, y-r, ellipseSpans(x, r, r), c.RGBA())
+}
+
+// DrawPie draws a filled sector of the circle of radius r centered at (x, y)
+// on the display. The sector goes clockwise from the angle start to the angle
+// end, in radian, like DrawArc.
+func DrawPie(dst Displayer, x, y, r int, start, end float32, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	arc := &arcDisplay{Displayer: dst, cx: x, cy: y, start: start, end: end}
+	drawSpans(arc, y-r, ellipseSpans(x, r, r), c.RGBA())
+}
+
+// DrawRoundRect draws a rectangle with rounded corners of radius r on the
+// display.
+func DrawRoundRect(dst Displayer, x, y, w, h, r int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	drawSpansOutline(dst, y, roundRectSpans(x, w, h, r), c.RGBA())
+}
+
+// DrawFilledRoundRect draws a filled rectangle with rounded corners of radius
+// r on the display.
+func DrawFilledRoundRect(dst Displayer, x, y, w, h, r int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	drawSpans(dst, y, roundRectSpans(x, w, h, r), c.RGBA())
+}
+
+// DrawPolygon draws the outline of a polygon on the display. The last point
+// is connected to the first one.
+func DrawPolygon(dst Displayer, points []image.Point, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	drawPolygonOutline(dst, points, c.RGBA())
+}
+
+// DrawFilledPolygon draws a filled polygon on the display. The pixels whose
+// center is inside the polygon by the even-odd rule are filled, and the
+// outline drawn by DrawPolygon is filled too.
+func DrawFilledPolygon(dst Displayer, points []image.Point, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	clr := c.RGBA()
+	fpoints := make([][2]float32, len(points))
+	for i, p := range points {
+		fpoints[i] = [2]float32{float32(p.X), float32(p.Y)}
+	}
+	fillPolygon(dst, fpoints, clr)
+	drawPolygonOutline(dst, points, clr)
+}
+
+// DrawThickLine draws a line of the given width on the display. The ends of
+// the line are square and cover the end points, like DrawLine.
+func DrawThickLine(dst Displayer, x1, y1, x2, y2, width int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	if width <= 1 {
+		tinydraw.Line(dst, int16(x1), int16(y1), int16(x2), int16(y2), c.RGBA())
+		return
+	}
+	// The line goes through the centers of the end pixels, and is extended by
+	// half a pixel at both ends so that the end pixels are covered.
+	ax, ay := float32(x1)+0.5, float32(y1)+0.5
+	bx, by := float32(x2)+0.5, float32(y2)+0.5
+	dx, dy := bx-ax, by-ay
+	l := math32.Hypot(dx, dy)
+	if l == 0 {
+		dx, dy, l = 1, 0, 1
+	}
+	ux, uy := dx/l/2, dy/l/2
+	ax, ay, bx, by = ax-ux, ay-uy, bx+ux, by+uy
+	// The normal of the line, half the width long.
+	nx, ny := -uy*float32(width), ux*float32(width)
+	fillPolygon(dst, [][2]float32{
+		{ax + nx, ay + ny},
+		{bx + nx, by + ny},
+		{bx - nx, by - ny},
+		{ax - nx, ay - ny},
+	}, c.RGBA())
+}
+
+// DrawDashedLine draws a dashed line on the display. The line starts with a
+// dash of dash pixels, followed by a gap of gap pixels, and so on.
+func DrawDashedLine(dst Displayer, x1, y1, x2, y2, dash, gap int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	clr := c.RGBA()
+	dash = max(dash, 1)
+	gap = max(gap, 0)
+
+	// Bresenham's algorithm, counting the pixels along the line.
+	dx, dy := abs(x2-x1), -abs(y2-y1)
+	sx, sy := 1, 1
+	if x1 > x2 {
+		sx = -1
+	}
+	if y1 > y2 {
+		sy = -1
+	}
+	e := dx + dy
+	for i := 0; ; i++ {
+		if i%(dash+gap) < dash {
+			dst.SetPixel(int16(x1), int16(y1), clr)
+		}
+		if x1 == x2 && y1 == y2 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			x1 += sx
+		}
+		if e2 <= dx {
+			e += dx
+			y1 += sy
+		}
+	}
+}
+
+// FloodFill fills the area of pixels of the same color as (x, y) with c,
+// going up, down, left and right. dst must implement PixelReader, such as an
+// Image or the screen, otherwise nothing is drawn.
+func FloodFill(dst Displayer, x, y int, c pixel.BaseColor) {
+	if isNil(dst) {
+		dst = display
+	}
+	r, ok := dst.(PixelReader)
+	if !ok {
+		return
+	}
+	w, h := dst.Size()
+	if x < 0 || int(w) <= x || y < 0 || int(h) <= y {
+		return
+	}
+	clr := c.RGBA()
+	target := r.PixelAt(int16(x), int16(y))
+
+	// The visited pixels, so that colors changed by the conversion to the
+	// format of dst don't make the fill loop.
+	visited := make([]bool, int(w)*int(h))
+	match := func(x, y int) bool {
+		return !visited[y*int(w)+x] && r.PixelAt(int16(x), int16(y)) == target
+	}
+
+	// Fill a span of the row, then push the spans of the rows above and
+	// below.
+	stack := []image.Point{{x, y}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !match(p.X, p.Y) {
+			continue
+		}
+		l, rr := p.X, p.X
+		for l > 0 && match(l-1, p.Y) {
+			l--
+		}
+		for rr < int(w)-1 && match(rr+1, p.Y) {
+			rr++
+		}
+		for xx := l; xx <= rr; xx++ {
+			visited[p.Y*int(w)+xx] = true
+			dst.SetPixel(int16(xx), int16(p.Y), clr)
+		}
+		for _, ny := range [2]int{p.Y - 1, p.Y + 1} {
+			if ny < 0 || int(h) <= ny {
+				continue
+			}
+			for xx := l; xx <= rr; xx++ {
+				// Push the first pixel of each span.
+				if match(xx, ny) && (xx == l || !match(xx-1, ny)) {
+					stack = append(stack, image.Point{xx, ny})
+				}
+			}
+		}
+	}
+}
+
+// span is the range of x from l to r, inclusive, of a row of a shape.
+type span struct {
+	l, r int
+}
+
+// ellipseSpans returns the rows of the ellipse centered at x with radii rx
+// and ry, from the top.
+func ellipseSpans(x, rx, ry int) []span {
+	rx, ry = max(rx, 0), max(ry, 0)
+	spans := make([]span, 2*ry+1)
+	for i := range spans {
+		dy := float32(i - ry)
+		w := rx
+		if ry > 0 {
+			w = int(float32(rx)*math32.Sqrt(1-dy*dy/float32(ry*ry)) + 0.5)
+		}
+		spans[i] = span{x - w, x + w}
+	}
+	return spans
+}
+
+// roundRectSpans returns the rows of the rounded rectangle at x of size
+// w x h with corners of radius r, from the top.
+func roundRectSpans(x, w, h, r int) []span {
+	if w <= 0 || h <= 0 {
+		return nil
+	}
+	r = min(max(r, 0), (w-1)/2, (h-1)/2)
+	spans := make([]span, h)
+	for i := range spans {
+		inset := 0
+		var dy int
+		switch {
+		case i < r:
+			dy = r - i
+		case i >= h-r:
+			dy = i - (h - 1 - r)
+		}
+		if dy > 0 {
+			inset = r - int(float32(r)*math32.Sqrt(1-float32(dy*dy)/float32(r*r))+0.5)
+		}
+		spans[i] = span{x + inset, x + w - 1 - inset}
+	}
+	return spans
+}
+
+// drawSpans fills the rows of a shape that starts at the row y.
+func drawSpans(dst Displayer, y int, spans []span, c color.RGBA) {
+	for i, s := range spans {
+		for x := s.l; x <= s.r; x++ {
+			dst.SetPixel(int16(x), int16(y+i), c)
+		}
+	}
+}
+
+// drawSpansOutline draws the pixels of a shape that starts at the row y
+// that are not surrounded by other pixels of the shape.
+func drawSpansOutline(dst Displayer, y int, spans []span, c color.RGBA) {
+	for i, s := range spans {
+		// The interior of the row is covered by the rows above and below.
+		il, ir := s.r+1, s.r
+		if 0 < i && i < len(spans)-1 {
+			il = max(s.l+1, spans[i-1].l, spans[i+1].l)
+			ir = min(s.r-1, spans[i-1].r, spans[i+1].r)
+		}
+		for x := s.l; x <= s.r; x++ {
+			if il <= x && x <= ir {
+				x = ir
+				continue
+			}
+			dst.SetPixel(int16(x), int16(y+i), c)
+		}
+	}
+}
+
+func drawPolygonOutline(dst Displayer, points []image.Point, c color.RGBA) {
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		tinydraw.Line(dst, int16(p.X), int16(p.Y), int16(q.X), int16(q.Y), c)
+	}
+}
+
+// fillPolygon fills the pixels whose center is inside the polygon by the
+// even-odd rule.
+func fillPolygon(dst Displayer, points [][2]float32, c color.RGBA) {
+	if len(points) < 3 {
+		return
+	}
+	minY, maxY := math32.Inf(1), math32.Inf(-1)
+	for _, p := range points {
+		minY, maxY = math32.Min(minY, p[1]), math32.Max(maxY, p[1])
+	}
+	_, h := dst.Size()
+	var xs []float32
+	for y := max(int(math32.Floor(minY)), 0); y < min(int(math32.Ceil(maxY)), int(h)); y++ {
+		fy := float32(y) + 0.5
+		xs = xs[:0]
+		for i, a := range points {
+			b := points[(i+1)%len(points)]
+			if (a[1] > fy) == (b[1] > fy) {
+				continue
+			}
+			xs = append(xs, a[0]+(fy-a[1])*(b[0]-a[0])/(b[1]-a[1]))
+		}
+		slices.Sort(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			// The pixels whose center is in [xs[i], xs[i+1]).
+			for x := int(math32.Ceil(xs[i] - 0.5)); float32(x)+0.5 < xs[i+1]; x++ {
+				dst.SetPixel(int16(x), int16(y), c)
+			}
+		}
+	}
+}
+
+// arcDisplay draws only the pixels whose angle around (cx, cy) is between
+// start and end.
+type arcDisplay struct {
+	Displayer
+	cx, cy     int
+	start, end float32
+}
+
+func (d *arcDisplay) SetPixel(x, y int16, c color.RGBA) {
+	dx, dy := int(x)-d.cx, int(y)-d.cy
+	if (dx != 0 || dy != 0) && !inAngle(math32.Atan2(float32(dy), float32(dx)), d.start, d.end) {
+		return
+	}
+	d.Displayer.SetPixel(x, y, c)
+}
+
+// inAngle reports whether the angle theta is in the clockwise range from
+// start to end.
+func inAngle(theta, start, end float32) bool {
+	const twoPi = 2 * math32.Pi
+	sweep := end - start
+	if sweep >= twoPi || sweep <= -twoPi {
+		return true
+	}
+	sweep = math32.Mod(sweep+twoPi, twoPi)
+	d := math32.Mod(math32.Mod(theta-start, twoPi)+twoPi, twoPi)
+	return d <= sweep
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
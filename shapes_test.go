@@ -0,0 +1,156 @@
+package koebiten_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers/pixel"
+)
+
+// setPixels returns the set pixels of img.
+func setPixels(img *koebiten.Image) map[image.Point]bool {
+	set := map[image.Point]bool{}
+	w, h := img.Size()
+	for y := 0; y < int(h); y++ {
+		for x := 0; x < int(w); x++ {
+			if img.PixelAt(int16(x), int16(y)) != (color.RGBA{A: 0xFF}) {
+				set[image.Pt(x, y)] = true
+			}
+		}
+	}
+	return set
+}
+
+func TestDrawOutlineInFilled(t *testing.T) {
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	for _, tc := range []struct {
+		name           string
+		outline, fill  func(dst koebiten.Displayer)
+		bounds         image.Rectangle
+		inside, corner image.Point
+	}{
+		{
+			name:    "ellipse",
+			outline: func(dst koebiten.Displayer) { koebiten.DrawEllipse(dst, 16, 10, 12, 6, white) },
+			fill:    func(dst koebiten.Displayer) { koebiten.DrawFilledEllipse(dst, 16, 10, 12, 6, white) },
+			bounds:  image.Rect(4, 4, 29, 17),
+			inside:  image.Pt(16, 10),
+			corner:  image.Pt(5, 5),
+		},
+		{
+			name:    "round rect",
+			outline: func(dst koebiten.Displayer) { koebiten.DrawRoundRect(dst, 2, 3, 20, 10, 4, white) },
+			fill:    func(dst koebiten.Displayer) { koebiten.DrawFilledRoundRect(dst, 2, 3, 20, 10, 4, white) },
+			bounds:  image.Rect(2, 3, 22, 13),
+			inside:  image.Pt(10, 8),
+			corner:  image.Pt(2, 3),
+		},
+		{
+			name: "polygon",
+			outline: func(dst koebiten.Displayer) {
+				koebiten.DrawPolygon(dst, []image.Point{{2, 2}, {20, 2}, {20, 12}, {11, 6}, {2, 12}}, white)
+			},
+			fill: func(dst koebiten.Displayer) {
+				koebiten.DrawFilledPolygon(dst, []image.Point{{2, 2}, {20, 2}, {20, 12}, {11, 6}, {2, 12}}, white)
+			},
+			bounds: image.Rect(2, 2, 21, 13),
+			inside: image.Pt(5, 5),
+			corner: image.Pt(11, 10),
+		},
+	} {
+		outline := koebiten.NewImage(32, 24)
+		tc.outline(outline)
+		filled := koebiten.NewImage(32, 24)
+		tc.fill(filled)
+
+		o, f := setPixels(outline), setPixels(filled)
+		var bounds image.Rectangle
+		for p := range f {
+			bounds = bounds.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+		}
+		if bounds != tc.bounds {
+			t.Errorf("%s: bounds = %v, want %v", tc.name, bounds, tc.bounds)
+		}
+		for p := range o {
+			if !f[p] {
+				t.Errorf("%s: pixel %v of the outline is not filled", tc.name, p)
+			}
+		}
+		if o[tc.inside] || !f[tc.inside] {
+			t.Errorf("%s: pixel %v inside: outline %v, filled %v", tc.name, tc.inside, o[tc.inside], f[tc.inside])
+		}
+		if f[tc.corner] {
+			t.Errorf("%s: pixel %v out of the shape is filled", tc.name, tc.corner)
+		}
+	}
+}
+
+func TestDrawPie(t *testing.T) {
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	img := koebiten.NewImage(32, 32)
+	// The bottom-right quarter.
+	koebiten.DrawPie(img, 16, 16, 10, 0, math32.Pi/2, white)
+	for p := range setPixels(img) {
+		if p.X < 16 || p.Y < 16 {
+			t.Errorf("pixel %v out of the quarter", p)
+		}
+	}
+	if !setPixels(img)[image.Pt(20, 20)] {
+		t.Errorf("pixel in the quarter is not set")
+	}
+
+	img = koebiten.NewImage(32, 32)
+	koebiten.DrawArc(img, 16, 16, 10, math32.Pi, 3*math32.Pi/2, white)
+	set := setPixels(img)
+	if !set[image.Pt(6, 16)] || !set[image.Pt(16, 6)] || set[image.Pt(26, 16)] {
+		t.Errorf("arc pixels = %v", set)
+	}
+}
+
+func TestDrawLines(t *testing.T) {
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	img := koebiten.NewImage(16, 16)
+	koebiten.DrawThickLine(img, 2, 5, 10, 5, 3, white)
+	want := map[image.Point]bool{}
+	for y := 4; y <= 6; y++ {
+		for x := 2; x <= 10; x++ {
+			want[image.Pt(x, y)] = true
+		}
+	}
+	got := setPixels(img)
+	if len(got) != len(want) {
+		t.Errorf("thick line: %d pixels, want %d", len(got), len(want))
+	}
+	for p := range want {
+		if !got[p] {
+			t.Errorf("thick line: pixel %v is not set", p)
+		}
+	}
+
+	img = koebiten.NewImage(16, 16)
+	koebiten.DrawDashedLine(img, 0, 0, 9, 0, 2, 3, white)
+	for x := 0; x < 10; x++ {
+		want := x%5 < 2
+		if got := setPixels(img)[image.Pt(x, 0)]; got != want {
+			t.Errorf("dashed line pixel %d = %v, want %v", x, got, want)
+		}
+	}
+}
+
+func TestFloodFill(t *testing.T) {
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	img := koebiten.NewImage(16, 16)
+	koebiten.DrawRect(img, 2, 2, 8, 8, white)
+	koebiten.FloodFill(img, 5, 5, white)
+
+	set := setPixels(img)
+	if got := len(set); got != 64 {
+		t.Errorf("%d pixels set, want 64", got)
+	}
+	if set[image.Pt(12, 12)] {
+		t.Errorf("pixel out of the rectangle is filled")
+	}
+}
@@ -0,0 +1,131 @@
+package koebiten
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+)
+
+// ErrInvalidSpriteSheet is returned when a sprite sheet atlas can't be parsed.
+var ErrInvalidSpriteSheet = errors.New("koebiten: invalid sprite sheet")
+
+// SpriteSheet is a set of frames cut out of one image.
+// Every frame is a sub-image that shares pixels with the sheet.
+type SpriteSheet struct {
+	image  *Image
+	frames []*Image
+	names  map[string]int
+}
+
+// NewSpriteSheet slices img into a grid of frames of the given size.
+// Frames are ordered from left to right, then from top to bottom, and the
+// cells at the right and bottom edges that are not complete are ignored.
+func NewSpriteSheet(img *Image, frameWidth, frameHeight int) *SpriteSheet {
+	s := &SpriteSheet{image: img}
+	if frameWidth <= 0 || frameHeight <= 0 {
+		return s
+	}
+	b := img.Bounds()
+	for y := b.Min.Y; y+frameHeight <= b.Max.Y; y += frameHeight {
+		for x := b.Min.X; x+frameWidth <= b.Max.X; x += frameWidth {
+			s.frames = append(s.frames, img.SubImage(image.Rect(x, y, x+frameWidth, y+frameHeight)))
+		}
+	}
+	return s
+}
+
+// NewSpriteSheetFromJSON slices img into the frames described by a JSON atlas
+// in the format exported by Aseprite and TexturePacker. The "frames" of the
+// atlas are either an array of objects with "filename" and "frame", or an
+// object that maps names to objects with "frame". Frames keep the order of
+// the atlas.
+//
+//	{"frames": {"walk 0": {"frame": {"x": 0, "y": 0, "w": 16, "h": 16}}, ...}}
+func NewSpriteSheetFromJSON(img *Image, data []byte) (*SpriteSheet, error) {
+	var atlas struct {
+		Frames json.RawMessage `json:"frames"`
+	}
+	if err := json.Unmarshal(data, &atlas); err != nil {
+		return nil, err
+	}
+
+	type atlasFrame struct {
+		Filename string `json:"filename"`
+		Frame    struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+			W int `json:"w"`
+			H int `json:"h"`
+		} `json:"frame"`
+	}
+	var frames []atlasFrame
+	switch raw := bytes.TrimSpace(atlas.Frames); {
+	case len(raw) > 0 && raw[0] == '[':
+		if err := json.Unmarshal(raw, &frames); err != nil {
+			return nil, err
+		}
+	case len(raw) > 0 && raw[0] == '{':
+		// Decode the object token by token to keep the order of the frames.
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			var f atlasFrame
+			if err := dec.Decode(&f); err != nil {
+				return nil, err
+			}
+			f.Filename, _ = t.(string)
+			frames = append(frames, f)
+		}
+	default:
+		return nil, ErrInvalidSpriteSheet
+	}
+
+	s := &SpriteSheet{
+		image: img,
+		names: map[string]int{},
+	}
+	b := img.Bounds()
+	for _, f := range frames {
+		r := image.Rect(f.Frame.X, f.Frame.Y, f.Frame.X+f.Frame.W, f.Frame.Y+f.Frame.H).Add(b.Min)
+		if f.Frame.W <= 0 || f.Frame.H <= 0 || !r.In(b) {
+			return nil, ErrInvalidSpriteSheet
+		}
+		if f.Filename != "" {
+			s.names[f.Filename] = len(s.frames)
+		}
+		s.frames = append(s.frames, img.SubImage(r))
+	}
+	return s, nil
+}
+
+// Image returns the image the frames are cut out of.
+func (s *SpriteSheet) Image() *Image {
+	return s.image
+}
+
+// Len returns the number of frames.
+func (s *SpriteSheet) Len() int {
+	return len(s.frames)
+}
+
+// Frame returns the i-th frame. It panics if i is out of range.
+func (s *SpriteSheet) Frame(i int) *Image {
+	return s.frames[i]
+}
+
+// FrameByName returns the frame with the given name in the JSON atlas, or
+// nil if there is no such frame.
+func (s *SpriteSheet) FrameByName(name string) *Image {
+	i, ok := s.names[name]
+	if !ok {
+		return nil
+	}
+	return s.frames[i]
+}
@@ -0,0 +1,77 @@
+package koebiten_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/sago35/koebiten"
+)
+
+func TestSubImage(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	img := koebiten.NewImage(8, 8)
+	sub := img.SubImage(image.Rect(2, 3, 6, 8))
+	if w, h := sub.Size(); w != 4 || h != 5 {
+		t.Fatalf("Size() = %d, %d, want 4, 5", w, h)
+	}
+
+	// Pixels are shared and the coordinates of the sub-image start at 0.
+	sub.SetPixel(1, 1, white)
+	if got := img.PixelAt(3, 4); got != white {
+		t.Errorf("pixel of the original image = %v, want white", got)
+	}
+	sub.SetPixel(4, 0, white)
+	if got := img.PixelAt(6, 3); got == white {
+		t.Errorf("SetPixel outside the sub-image changed the original image")
+	}
+
+	// A sub-image of a sub-image uses the coordinates of the original image.
+	subsub := sub.SubImage(image.Rect(0, 0, 4, 5))
+	if got, want := subsub.Bounds(), image.Rect(2, 3, 4, 5); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+	if got := subsub.PixelAt(1, 1); got != white {
+		t.Errorf("pixel of the nested sub-image = %v, want white", got)
+	}
+}
+
+func TestSpriteSheet(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	img := koebiten.NewImage(10, 8)
+	img.SetPixel(4, 4, white)
+
+	s := koebiten.NewSpriteSheet(img, 4, 4)
+	if got := s.Len(); got != 4 {
+		t.Fatalf("Len() = %d, want 4", got)
+	}
+	if got := s.Frame(3).PixelAt(0, 0); got != white {
+		t.Errorf("Frame(3) pixel = %v, want white", got)
+	}
+
+	for _, data := range []string{
+		`{"frames": {"b": {"frame": {"x": 4, "y": 4, "w": 2, "h": 2}}, "a": {"frame": {"x": 0, "y": 0, "w": 4, "h": 4}}}}`,
+		`{"frames": [{"filename": "b", "frame": {"x": 4, "y": 4, "w": 2, "h": 2}}, {"filename": "a", "frame": {"x": 0, "y": 0, "w": 4, "h": 4}}]}`,
+	} {
+		s, err := koebiten.NewSpriteSheetFromJSON(img, []byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.Len(); got != 2 {
+			t.Fatalf("Len() = %d, want 2", got)
+		}
+		if got := s.Frame(0).PixelAt(0, 0); got != white {
+			t.Errorf("Frame(0) is not the first frame of the atlas")
+		}
+		if w, _ := s.FrameByName("a").Size(); w != 4 {
+			t.Errorf("FrameByName(\"a\") has width %d, want 4", w)
+		}
+		if s.FrameByName("c") != nil {
+			t.Errorf("FrameByName(\"c\") is not nil")
+		}
+	}
+
+	if _, err := koebiten.NewSpriteSheetFromJSON(img, []byte(`{"frames": [{"frame": {"x": 8, "y": 0, "w": 4, "h": 4}}]}`)); err == nil {
+		t.Errorf("frame outside the image: no error")
+	}
+}
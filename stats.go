@@ -0,0 +1,161 @@
+package koebiten
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// PhaseStats holds the statistics of one phase of the main loop over the
+// latest frames.
+type PhaseStats struct {
+	// Samples is the number of measurements the statistics are based on.
+	Samples int
+
+	Min time.Duration
+	Avg time.Duration
+	Max time.Duration
+
+	// P50, P90 and P99 are the 50th, 90th and 99th percentiles.
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+}
+
+// String returns a string representation of PhaseStats.
+func (s PhaseStats) String() string {
+	return fmt.Sprintf("min %dus avg %dus p90 %dus max %dus",
+		s.Min.Microseconds(), s.Avg.Microseconds(), s.P90.Microseconds(), s.Max.Microseconds())
+}
+
+// FrameStats holds the time spent in each phase of the main loop.
+//
+// Update is measured for every tick. Draw and Display are measured only for
+// the frames that are actually drawn.
+type FrameStats struct {
+	Update  PhaseStats
+	Draw    PhaseStats
+	Display PhaseStats
+
+	// Ticks is the total number of ticks since the program started.
+	Ticks uint64
+	// SkippedFrames is the total number of frames skipped to catch up with the TPS.
+	SkippedFrames uint64
+}
+
+// ReadFrameStats populates stats with the statistics of the latest 64
+// measurements of each phase.
+//
+// ReadFrameStats can be called from Update or Draw, for example to show an
+// on-screen overlay.
+//
+// ReadFrameStats is concurrent safe.
+func ReadFrameStats(stats *FrameStats) {
+	theFrameStats.m.Lock()
+	defer theFrameStats.m.Unlock()
+
+	theFrameStats.update.read(&stats.Update)
+	theFrameStats.draw.read(&stats.Draw)
+	theFrameStats.display.read(&stats.Display)
+	stats.Ticks = theFrameStats.ticks
+	stats.SkippedFrames = theFrameStats.skipped
+}
+
+var theFrameStats = &frameStats{}
+
+type frameStats struct {
+	update  phaseSamples
+	draw    phaseSamples
+	display phaseSamples
+	ticks   uint64
+	skipped uint64
+
+	m sync.Mutex
+}
+
+func (f *frameStats) addUpdate(d time.Duration) {
+	f.m.Lock()
+	f.update.add(d)
+	f.ticks++
+	f.m.Unlock()
+}
+
+func (f *frameStats) addFrame(draw, display time.Duration) {
+	f.m.Lock()
+	f.draw.add(draw)
+	f.display.add(display)
+	f.m.Unlock()
+}
+
+// numTicks returns the total number of ticks.
+func (f *frameStats) numTicks() uint64 {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.ticks
+}
+
+func (f *frameStats) addSkip() {
+	f.m.Lock()
+	f.skipped++
+	f.m.Unlock()
+}
+
+// phaseSamples is a ring buffer of durations in microseconds.
+type phaseSamples struct {
+	samples [64]uint32
+	n       int
+}
+
+func (p *phaseSamples) add(d time.Duration) {
+	p.samples[p.n%len(p.samples)] = uint32(d.Microseconds())
+	p.n++
+}
+
+func (p *phaseSamples) read(s *PhaseStats) {
+	n := p.n
+	if n > len(p.samples) {
+		n = len(p.samples)
+	}
+	*s = PhaseStats{Samples: n}
+	if n == 0 {
+		return
+	}
+
+	// Insertion sort of a copy, so that nothing is allocated.
+	var sorted [len(p.samples)]uint32
+	sum := uint64(0)
+	for i := 0; i < n; i++ {
+		v := p.samples[i]
+		sum += uint64(v)
+		j := i
+		for ; j > 0 && sorted[j-1] > v; j-- {
+			sorted[j] = sorted[j-1]
+		}
+		sorted[j] = v
+	}
+
+	us := func(v uint32) time.Duration { return time.Duration(v) * time.Microsecond }
+	percentile := func(q int) time.Duration {
+		i := (n*q+99)/100 - 1
+		if i < 0 {
+			i = 0
+		}
+		return us(sorted[i])
+	}
+	s.Min = us(sorted[0])
+	s.Max = us(sorted[n-1])
+	s.Avg = time.Duration(sum/uint64(n)) * time.Microsecond
+	s.P50 = percentile(50)
+	s.P90 = percentile(90)
+	s.P99 = percentile(99)
+}
+
+// printFrameStats prints the frame statistics. It is used by the koebiten_benchmark tag.
+func printFrameStats() {
+	var s FrameStats
+	ReadFrameStats(&s)
+	fmt.Printf("%.1f TPS, skipped %d\n", ActualTPS(), s.SkippedFrames)
+	fmt.Printf("  update  : %s\n", s.Update)
+	fmt.Printf("  draw    : %s\n", s.Draw)
+	fmt.Printf("  display : %s\n", s.Display)
+}
@@ -0,0 +1,42 @@
+package koebiten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPhaseSamples(t *testing.T) {
+	var p phaseSamples
+	var s PhaseStats
+	p.read(&s)
+	if g, e := s.Samples, 0; g != e {
+		t.Errorf("got %d want %d", g, e)
+	}
+
+	// 1ms .. 100ms, the oldest 36 samples are dropped from the window.
+	for i := 100; i >= 1; i-- {
+		p.add(time.Duration(i) * time.Millisecond)
+	}
+	p.read(&s)
+
+	tests := []struct {
+		name string
+		got  time.Duration
+		want time.Duration
+	}{
+		{"min", s.Min, 1 * time.Millisecond},
+		{"max", s.Max, 64 * time.Millisecond},
+		{"avg", s.Avg, 32500 * time.Microsecond},
+		{"p50", s.P50, 32 * time.Millisecond},
+		{"p90", s.P90, 58 * time.Millisecond},
+		{"p99", s.P99, 64 * time.Millisecond},
+	}
+	if g, e := s.Samples, 64; g != e {
+		t.Errorf("samples: got %d want %d", g, e)
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %v want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
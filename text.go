@@ -0,0 +1,235 @@
+package koebiten
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"unicode/utf8"
+
+	"tinygo.org/x/drivers/pixel"
+	"tinygo.org/x/tinyfont"
+)
+
+// Align represents the alignment of text.
+type Align int
+
+const (
+	// AlignStart aligns the text to the left or to the top.
+	AlignStart Align = iota
+
+	// AlignCenter centers the text.
+	AlignCenter
+
+	// AlignEnd aligns the text to the right or to the bottom.
+	AlignEnd
+)
+
+// DrawTextOptions represents options for DrawTextWithOptions.
+//
+// The text is laid out in lines of LineSpacing pixels. The top of the text is
+// above the baseline of the first line, the y of DrawText, by the height of
+// the tallest printable ASCII character of the font.
+type DrawTextOptions struct {
+	// X and Y are the position of the text when Rect is empty. The text is
+	// aligned to this point, so with AlignStart, it is the top-left corner of
+	// the text, and with AlignEnd, the bottom-right corner.
+	// The default (zero) value is (0, 0).
+	X, Y int
+
+	// Rect is the box the text is aligned in.
+	// The default (zero) value is an empty rectangle, which means that the
+	// text is aligned to (X, Y).
+	Rect image.Rectangle
+
+	// HorizontalAlign is the alignment of each line.
+	// The default (zero) value is AlignStart.
+	HorizontalAlign Align
+
+	// VerticalAlign is the alignment of the lines.
+	// The default (zero) value is AlignStart.
+	VerticalAlign Align
+
+	// LineSpacing is the distance between the baselines of two lines.
+	// The default (zero) value is 0, which means the YAdvance of the font.
+	LineSpacing int
+
+	// Wrap breaks the lines at spaces so that they fit in the width of Rect.
+	// Words longer than the width are broken anywhere.
+	// The default (zero) value is false.
+	Wrap bool
+
+	// Clip draws only the pixels in Rect.
+	// The default (zero) value is false.
+	Clip bool
+}
+
+// DrawTextWithOptions draws text on the display with alignment, wrapping and
+// clipping. Lines are separated by '\n'. If font is nil, tinyfont.Org01 is
+// used.
+func DrawTextWithOptions(dst Displayer, str string, font tinyfont.Fonter, c pixel.BaseColor, options DrawTextOptions) {
+	if isNil(dst) {
+		dst = display
+	}
+	if font == nil {
+		font = &tinyfont.Org01
+	}
+	spacing := lineSpacingOf(font, options.LineSpacing)
+
+	var lines []string
+	if options.Wrap && !options.Rect.Empty() {
+		lines = WrapText(str, font, options.Rect.Dx())
+	} else {
+		lines = strings.Split(str, "\n")
+	}
+
+	r := options.Rect
+	if r.Empty() {
+		r = image.Rect(options.X, options.Y, options.X, options.Y)
+	} else if options.Clip {
+		dst = &clipDisplay{Displayer: dst, rect: r}
+	}
+
+	height := (len(lines)-1)*spacing + int(font.GetYAdvance())
+	top := r.Min.Y + alignOffset(options.VerticalAlign, r.Dy(), height)
+	baseline := top + fontAscent(font)
+	clr := c.RGBA()
+	for _, line := range lines {
+		if options.Clip && !options.Rect.Empty() {
+			// Skip the lines out of the box.
+			if baseline-spacing >= r.Max.Y {
+				break
+			}
+			if baseline+spacing < r.Min.Y {
+				baseline += spacing
+				continue
+			}
+		}
+		x := r.Min.X + alignOffset(options.HorizontalAlign, r.Dx(), textWidth(font, line))
+		for _, ch := range line {
+			glyph := font.GetGlyph(ch)
+			glyph.Draw(dst, int16(x), int16(baseline), clr)
+			x += int(glyph.Info().XAdvance)
+		}
+		baseline += spacing
+	}
+}
+
+// MeasureText returns the size of the box of str drawn with font, as laid
+// out by DrawTextWithOptions. The width is the advance of the longest line.
+// If lineSpacing is 0, the YAdvance of the font is used.
+func MeasureText(str string, font tinyfont.Fonter, lineSpacing int) (width, height int) {
+	lines := strings.Split(str, "\n")
+	for _, line := range lines {
+		width = max(width, textWidth(font, line))
+	}
+	height = (len(lines)-1)*lineSpacingOf(font, lineSpacing) + int(font.GetYAdvance())
+	return width, height
+}
+
+// TextBounds returns the bounds of the pixels of str drawn with font. The
+// bounds are relative to the top-left corner of the box of the text, as laid
+// out by DrawTextWithOptions with AlignStart. If lineSpacing is 0, the
+// YAdvance of the font is used.
+func TextBounds(str string, font tinyfont.Fonter, lineSpacing int) image.Rectangle {
+	var bounds image.Rectangle
+	baseline := fontAscent(font)
+	for _, line := range strings.Split(str, "\n") {
+		x := 0
+		for _, ch := range line {
+			info := font.GetGlyph(ch).Info()
+			gx, gy := x+int(info.XOffset), baseline+int(info.YOffset)
+			bounds = bounds.Union(image.Rect(gx, gy, gx+int(info.Width), gy+int(info.Height)))
+			x += int(info.XAdvance)
+		}
+		baseline += lineSpacingOf(font, lineSpacing)
+	}
+	return bounds
+}
+
+// WrapText breaks str into lines that fit in width pixels when drawn with
+// font. Lines are broken at '\n' and at spaces, and words longer than width
+// are broken anywhere.
+func WrapText(str string, font tinyfont.Fonter, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(str, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			if line != "" {
+				if textWidth(font, line+" "+word) <= width {
+					line += " " + word
+					continue
+				}
+				lines = append(lines, line)
+			}
+			// Break the words that don't fit in a line.
+			for textWidth(font, word) > width {
+				n := 0
+				for i := range word {
+					if i > 0 && textWidth(font, word[:i]) > width {
+						break
+					}
+					n = i
+				}
+				if n == 0 {
+					// Keep at least one character per line.
+					_, n = utf8.DecodeRuneInString(word)
+				}
+				lines = append(lines, word[:n])
+				word = word[n:]
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// textWidth returns the advance of a line of text.
+func textWidth(font tinyfont.Fonter, line string) int {
+	w := 0
+	for _, ch := range line {
+		w += int(font.GetGlyph(ch).Info().XAdvance)
+	}
+	return w
+}
+
+// fontAscent returns the height of the printable ASCII characters of font
+// above the baseline.
+func fontAscent(font tinyfont.Fonter) int {
+	ascent := 0
+	for ch := rune(0x21); ch < 0x7F; ch++ {
+		ascent = max(ascent, -int(font.GetGlyph(ch).Info().YOffset))
+	}
+	return ascent
+}
+
+func lineSpacingOf(font tinyfont.Fonter, lineSpacing int) int {
+	if lineSpacing == 0 {
+		return int(font.GetYAdvance())
+	}
+	return lineSpacing
+}
+
+// alignOffset returns the offset of a content of size content in a box of
+// size box.
+func alignOffset(align Align, box, content int) int {
+	switch align {
+	case AlignCenter:
+		return (box - content) / 2
+	case AlignEnd:
+		return box - content
+	}
+	return 0
+}
+
+// clipDisplay draws only the pixels in a rectangle of a Displayer.
+type clipDisplay struct {
+	Displayer
+	rect image.Rectangle
+}
+
+func (d *clipDisplay) SetPixel(x, y int16, c color.RGBA) {
+	if image.Pt(int(x), int(y)).In(d.rect) {
+		d.Displayer.SetPixel(x, y, c)
+	}
+}
@@ -0,0 +1,106 @@
+package koebiten_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers/pixel"
+	"tinygo.org/x/tinyfont"
+)
+
+// inkBounds returns the bounds of the set pixels of img.
+func inkBounds(img *koebiten.Image) image.Rectangle {
+	var r image.Rectangle
+	w, h := img.Size()
+	for y := 0; y < int(h); y++ {
+		for x := 0; x < int(w); x++ {
+			if img.PixelAt(int16(x), int16(y)) != (color.RGBA{A: 0xFF}) {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return r
+}
+
+func TestMeasureText(t *testing.T) {
+	font := &tinyfont.Org01
+	w, h := koebiten.MeasureText("ab\nabc", font, 10)
+	_, want := tinyfont.LineWidth(font, "abc")
+	if w != int(want) || h != 10+int(font.GetYAdvance()) {
+		t.Errorf("MeasureText() = %d, %d, want %d, %d", w, h, want, 10+int(font.GetYAdvance()))
+	}
+
+	// The bounds match the pixels drawn at the top-left corner.
+	img := koebiten.NewImage(64, 32)
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	koebiten.DrawTextWithOptions(img, "Hi\ngo", font, white, koebiten.DrawTextOptions{X: 4, Y: 4})
+	if got, want := inkBounds(img), koebiten.TextBounds("Hi\ngo", font, 0).Add(image.Pt(4, 4)); got != want {
+		t.Errorf("pixels drawn in %v, want %v", got, want)
+	}
+}
+
+func TestDrawTextAlign(t *testing.T) {
+	font := &tinyfont.Org01
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	box := image.Rect(10, 10, 50, 30)
+	bounds := koebiten.TextBounds("12", font, 0)
+	w, h := koebiten.MeasureText("12", font, 0)
+
+	for _, tc := range []struct {
+		h, v koebiten.Align
+		pos  image.Point
+	}{
+		{koebiten.AlignStart, koebiten.AlignStart, image.Pt(10, 10)},
+		{koebiten.AlignCenter, koebiten.AlignCenter, image.Pt(10+(40-w)/2, 10+(20-h)/2)},
+		{koebiten.AlignEnd, koebiten.AlignEnd, image.Pt(50-w, 30-h)},
+	} {
+		img := koebiten.NewImage(64, 40)
+		koebiten.DrawTextWithOptions(img, "12", font, white, koebiten.DrawTextOptions{
+			Rect:            box,
+			HorizontalAlign: tc.h,
+			VerticalAlign:   tc.v,
+		})
+		if got, want := inkBounds(img), bounds.Add(tc.pos); got != want {
+			t.Errorf("align %d, %d: pixels drawn in %v, want %v", tc.h, tc.v, got, want)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	font := &tinyfont.Org01
+	width, _ := koebiten.MeasureText("aaa bbb", font, 0)
+	lines := koebiten.WrapText("aaa bbb ccc\nd", font, width)
+	want := []string{"aaa bbb", "ccc", "d"}
+	if len(lines) != len(want) {
+		t.Fatalf("WrapText() = %q, want %q", lines, want)
+	}
+	for i := range lines {
+		if lines[i] != want[i] {
+			t.Errorf("WrapText() = %q, want %q", lines, want)
+		}
+	}
+
+	// A long word is broken.
+	w, _ := koebiten.MeasureText("aa", font, 0)
+	if got := koebiten.WrapText("aaaaa", font, w); len(got) != 3 {
+		t.Errorf("WrapText() = %q, want 3 lines", got)
+	}
+}
+
+func TestDrawTextClip(t *testing.T) {
+	font := &tinyfont.Org01
+	white := pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+	img := koebiten.NewImage(64, 64)
+	box := image.Rect(8, 8, 24, 14)
+	koebiten.DrawTextWithOptions(img, "the quick brown fox jumps over the lazy dog", font, white, koebiten.DrawTextOptions{
+		Rect: box,
+		Wrap: true,
+		Clip: true,
+	})
+	got := inkBounds(img)
+	if got.Empty() || !got.In(box) {
+		t.Errorf("pixels drawn in %v, want in %v", got, box)
+	}
+}
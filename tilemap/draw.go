@@ -0,0 +1,110 @@
+package tilemap
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+)
+
+// DrawOptions represents options for Map.DrawWithOptions and Map.DrawLayer.
+type DrawOptions struct {
+	// GeoM is the geometry matrix from the map to the destination. Translate
+	// it by the negated position of the camera to scroll the map.
+	// The default (zero) value is identity, which draws the top-left corner
+	// of the map at the top-left corner of the destination.
+	GeoM koebiten.GeoM
+}
+
+// Draw draws the visible tile layers of the map to dst, with the pixel
+// (cameraX, cameraY) of the map at the top-left corner of dst. If dst is nil,
+// the map is drawn to the screen.
+func (m *Map) Draw(dst koebiten.Displayer, cameraX, cameraY int) {
+	var options DrawOptions
+	options.GeoM.Translate(-float32(cameraX), -float32(cameraY))
+	m.DrawWithOptions(dst, options)
+}
+
+// DrawWithOptions draws the visible tile layers of the map to dst with
+// options. If dst is nil, the map is drawn to the screen.
+func (m *Map) DrawWithOptions(dst koebiten.Displayer, options DrawOptions) {
+	for _, l := range m.Layers {
+		if l.Visible {
+			m.DrawLayer(dst, l, options)
+		}
+	}
+}
+
+// DrawLayer draws the tile layer l to dst with options, even if it is not
+// visible. Only the tiles that can be seen in dst are drawn. If dst is nil,
+// the layer is drawn to the screen.
+func (m *Map) DrawLayer(dst koebiten.Displayer, l *Layer, options DrawOptions) {
+	dst = koebiten.DisplayerOrScreen(dst)
+	if dst == nil {
+		return
+	}
+	g := options.GeoM
+	if !g.IsInvertible() || m.TileWidth <= 0 || m.TileHeight <= 0 {
+		return
+	}
+
+	// The bounding box of dst on the layer.
+	w, h := dst.Size()
+	inv := g
+	inv.Invert()
+	minX, minY := math32.Inf(1), math32.Inf(1)
+	maxX, maxY := math32.Inf(-1), math32.Inf(-1)
+	for _, p := range [4][2]float32{{0, 0}, {float32(w), 0}, {0, float32(h)}, {float32(w), float32(h)}} {
+		x, y := inv.Apply(p[0], p[1])
+		minX, maxX = math32.Min(minX, x-l.OffsetX), math32.Max(maxX, x-l.OffsetX)
+		minY, maxY = math32.Min(minY, y-l.OffsetY), math32.Max(maxY, y-l.OffsetY)
+	}
+
+	// Tiles larger than the cells are anchored at the bottom-left corner of
+	// their cell, so they reach the cells on the right and above.
+	extraX, extraY := 0, 0
+	for _, t := range m.Tilesets {
+		extraX = max(extraX, (t.TileWidth-1)/m.TileWidth)
+		extraY = max(extraY, (t.TileHeight-1)/m.TileHeight)
+	}
+	tw, th := float32(m.TileWidth), float32(m.TileHeight)
+	x0 := max(int(math32.Floor(minX/tw))-extraX, 0)
+	y0 := max(int(math32.Floor(minY/th)), 0)
+	x1 := min(int(math32.Ceil(maxX/tw)), l.Width)
+	y1 := min(int(math32.Ceil(maxY/th))+extraY, l.Height)
+
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			gid := l.Tiles[y*l.Width+x]
+			img := m.TileImage(gid)
+			if img == nil {
+				continue
+			}
+			iw, ih := img.Size()
+			var op koebiten.DrawImageOptions
+			flip(&op.GeoM, gid, float32(iw), float32(ih))
+			op.GeoM.Translate(float32(x*m.TileWidth)+l.OffsetX, float32((y+1)*m.TileHeight-int(ih))+l.OffsetY)
+			op.GeoM.Concat(g)
+			img.DrawImage(dst, op)
+		}
+	}
+}
+
+// flip sets g to the flips of the global tile ID gid, for a tile of size
+// (w, h). The tile is flipped diagonally first, then horizontally, then
+// vertically, as Tiled does.
+func flip(g *koebiten.GeoM, gid uint32, w, h float32) {
+	if gid&FlippedDiagonally != 0 {
+		g.SetElement(0, 0, 0)
+		g.SetElement(0, 1, 1)
+		g.SetElement(1, 0, 1)
+		g.SetElement(1, 1, 0)
+		w, h = h, w
+	}
+	if gid&FlippedHorizontally != 0 {
+		g.Scale(-1, 1)
+		g.Translate(w, 0)
+	}
+	if gid&FlippedVertically != 0 {
+		g.Scale(1, -1)
+		g.Translate(0, h)
+	}
+}
@@ -0,0 +1,217 @@
+package tilemap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+)
+
+type jsonMap struct {
+	Width       int            `json:"width"`
+	Height      int            `json:"height"`
+	TileWidth   int            `json:"tilewidth"`
+	TileHeight  int            `json:"tileheight"`
+	Orientation string         `json:"orientation"`
+	Infinite    bool           `json:"infinite"`
+	Tilesets    []jsonTileset  `json:"tilesets"`
+	Layers      []jsonLayer    `json:"layers"`
+	Properties  []jsonProperty `json:"properties"`
+}
+
+type jsonTileset struct {
+	FirstGID   uint32 `json:"firstgid"`
+	Source     string `json:"source"`
+	Name       string `json:"name"`
+	TileWidth  int    `json:"tilewidth"`
+	TileHeight int    `json:"tileheight"`
+	TileCount  int    `json:"tilecount"`
+	Columns    int    `json:"columns"`
+	Margin     int    `json:"margin"`
+	Spacing    int    `json:"spacing"`
+	Image      string `json:"image"`
+	Tiles      []struct {
+		ID    int    `json:"id"`
+		Image string `json:"image"`
+	} `json:"tiles"`
+}
+
+type jsonLayer struct {
+	Type        string          `json:"type"`
+	Name        string          `json:"name"`
+	Visible     *bool           `json:"visible"`
+	OffsetX     float32         `json:"offsetx"`
+	OffsetY     float32         `json:"offsety"`
+	Width       int             `json:"width"`
+	Height      int             `json:"height"`
+	Data        json.RawMessage `json:"data"`
+	Encoding    string          `json:"encoding"`
+	Compression string          `json:"compression"`
+	Objects     []jsonObject    `json:"objects"`
+	Layers      []jsonLayer     `json:"layers"`
+	Properties  []jsonProperty  `json:"properties"`
+}
+
+type jsonObject struct {
+	ID         int            `json:"id"`
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Class      string         `json:"class"`
+	X          float32        `json:"x"`
+	Y          float32        `json:"y"`
+	Width      float32        `json:"width"`
+	Height     float32        `json:"height"`
+	Rotation   float32        `json:"rotation"`
+	GID        uint32         `json:"gid"`
+	Ellipse    bool           `json:"ellipse"`
+	Point      bool           `json:"point"`
+	Polygon    []Point        `json:"polygon"`
+	Polyline   []Point        `json:"polyline"`
+	Properties []jsonProperty `json:"properties"`
+}
+
+type jsonProperty struct {
+	Name  string `json:"name"`
+	Value any    `json:"value"`
+}
+
+func loadJSON(fsys fs.FS, name string) (*Map, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var jm jsonMap
+	if err := json.Unmarshal(data, &jm); err != nil {
+		return nil, err
+	}
+	if err := checkMap(jm.Orientation, jm.Infinite); err != nil {
+		return nil, err
+	}
+
+	m := &Map{
+		Width:      jm.Width,
+		Height:     jm.Height,
+		TileWidth:  jm.TileWidth,
+		TileHeight: jm.TileHeight,
+		Properties: jsonProperties(jm.Properties),
+	}
+	for _, jt := range jm.Tilesets {
+		file, src := name, jt.source()
+		if jt.Source != "" {
+			file = path.Join(path.Dir(name), jt.Source)
+			if src, err = readTilesetFile(fsys, file); err != nil {
+				return nil, err
+			}
+		}
+		if err := m.addTileset(fsys, file, jt.FirstGID, src); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.addJSONLayers(jm.Layers, group{visible: true}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (jt *jsonTileset) source() *tilesetSource {
+	src := &tilesetSource{
+		name:       jt.Name,
+		tileWidth:  jt.TileWidth,
+		tileHeight: jt.TileHeight,
+		tileCount:  jt.TileCount,
+		columns:    jt.Columns,
+		margin:     jt.Margin,
+		spacing:    jt.Spacing,
+		image:      jt.Image,
+		tileImages: map[int]string{},
+	}
+	for _, t := range jt.Tiles {
+		if t.Image != "" {
+			src.tileImages[t.ID] = t.Image
+		}
+	}
+	return src
+}
+
+func (m *Map) addJSONLayers(layers []jsonLayer, g group) error {
+	for _, jl := range layers {
+		visible := g.visible && (jl.Visible == nil || *jl.Visible)
+		offsetX, offsetY := g.offsetX+jl.OffsetX, g.offsetY+jl.OffsetY
+		switch jl.Type {
+		case "tilelayer":
+			var tiles []uint32
+			if raw := bytes.TrimSpace(jl.Data); len(raw) > 0 && raw[0] == '[' {
+				if err := json.Unmarshal(raw, &tiles); err != nil {
+					return err
+				}
+				if len(tiles) != jl.Width*jl.Height {
+					return fmt.Errorf("tilemap: layer has %d tiles, want %d", len(tiles), jl.Width*jl.Height)
+				}
+			} else {
+				var s string
+				if err := json.Unmarshal(raw, &s); err != nil {
+					return err
+				}
+				var err error
+				if tiles, err = decodeTiles(s, jl.Encoding, jl.Compression, jl.Width*jl.Height); err != nil {
+					return err
+				}
+			}
+			m.Layers = append(m.Layers, &Layer{
+				Name:       jl.Name,
+				Visible:    visible,
+				OffsetX:    offsetX,
+				OffsetY:    offsetY,
+				Width:      jl.Width,
+				Height:     jl.Height,
+				Tiles:      tiles,
+				Properties: jsonProperties(jl.Properties),
+			})
+		case "objectgroup":
+			l := &ObjectLayer{
+				Name:       jl.Name,
+				Visible:    visible,
+				OffsetX:    offsetX,
+				OffsetY:    offsetY,
+				Properties: jsonProperties(jl.Properties),
+			}
+			for _, jo := range jl.Objects {
+				o := &Object{
+					ID:         jo.ID,
+					Name:       jo.Name,
+					Type:       jo.Type,
+					X:          jo.X,
+					Y:          jo.Y,
+					Width:      jo.Width,
+					Height:     jo.Height,
+					Rotation:   jo.Rotation,
+					GID:        jo.GID,
+					Ellipse:    jo.Ellipse,
+					Point:      jo.Point,
+					Polygon:    jo.Polygon,
+					Polyline:   jo.Polyline,
+					Properties: jsonProperties(jo.Properties),
+				}
+				if o.Type == "" {
+					o.Type = jo.Class
+				}
+				l.Objects = append(l.Objects, o)
+			}
+			m.ObjectLayers = append(m.ObjectLayers, l)
+		case "group":
+			if err := m.addJSONLayers(jl.Layers, group{visible: visible, offsetX: offsetX, offsetY: offsetY}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonProperties(props []jsonProperty) Properties {
+	p := Properties{}
+	for _, prop := range props {
+		p[prop.Name] = fmt.Sprint(prop.Value)
+	}
+	return p
+}
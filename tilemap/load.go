@@ -0,0 +1,162 @@
+package tilemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sago35/koebiten"
+)
+
+// tilesetSource is a tileset as it is stored in a map or a tileset file.
+type tilesetSource struct {
+	name       string
+	tileWidth  int
+	tileHeight int
+	tileCount  int
+	columns    int
+	margin     int
+	spacing    int
+
+	// image is the path of the image of the whole tileset, and tileImages
+	// are the paths of the images of the tiles of a collection of images.
+	image      string
+	tileImages map[int]string
+}
+
+// addTileset loads the images of src, whose paths are relative to the file
+// name, and adds the tileset to m.
+func (m *Map) addTileset(fsys fs.FS, name string, firstGID uint32, src *tilesetSource) error {
+	t := &Tileset{
+		FirstGID:   firstGID,
+		Name:       src.name,
+		TileWidth:  src.tileWidth,
+		TileHeight: src.tileHeight,
+		TileCount:  src.tileCount,
+	}
+	if src.image != "" {
+		if t.TileWidth <= 0 || t.TileHeight <= 0 {
+			return fmt.Errorf("%w: tileset %q has no tile size", ErrUnsupported, src.name)
+		}
+		img, err := koebiten.LoadImage(fsys, path.Join(path.Dir(name), src.image))
+		if err != nil {
+			return err
+		}
+		t.cut(img, src.margin, src.spacing, src.columns)
+	} else {
+		t.tiles = map[int]*koebiten.Image{}
+		for id, source := range src.tileImages {
+			img, err := koebiten.LoadImage(fsys, path.Join(path.Dir(name), source))
+			if err != nil {
+				return err
+			}
+			t.tiles[id] = img
+		}
+	}
+
+	m.Tilesets = append(m.Tilesets, t)
+	sort.Slice(m.Tilesets, func(i, j int) bool {
+		return m.Tilesets[i].FirstGID < m.Tilesets[j].FirstGID
+	})
+	return nil
+}
+
+// readTilesetFile reads an external tileset, in TSX or in JSON.
+func readTilesetFile(fsys fs.FS, name string) (*tilesetSource, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	if path.Ext(name) == ".tsx" {
+		var tt tmxTileset
+		if err := xml.Unmarshal(data, &tt); err != nil {
+			return nil, err
+		}
+		return tt.source(), nil
+	}
+	var jt jsonTileset
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return nil, err
+	}
+	return jt.source(), nil
+}
+
+// group is the state inherited by the layers of a group.
+type group struct {
+	visible          bool
+	offsetX, offsetY float32
+}
+
+// decodeTiles decodes the global tile IDs of a layer of n cells, stored in
+// CSV or in base64 with an optional compression.
+func decodeTiles(data, encoding, compression string, n int) ([]uint32, error) {
+	tiles := make([]uint32, 0, n)
+	switch encoding {
+	case "csv":
+		for _, f := range strings.Split(data, ",") {
+			f = strings.TrimSpace(f)
+			if f == "" {
+				continue
+			}
+			gid, err := strconv.ParseUint(f, 10, 32)
+			if err != nil {
+				return nil, err
+			}
+			tiles = append(tiles, uint32(gid))
+		}
+	case "base64":
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+		if err != nil {
+			return nil, err
+		}
+		var r io.Reader = bytes.NewReader(b)
+		switch compression {
+		case "":
+		case "zlib":
+			if r, err = zlib.NewReader(r); err != nil {
+				return nil, err
+			}
+		case "gzip":
+			if r, err = gzip.NewReader(r); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, fmt.Errorf("%w: %s compression", ErrUnsupported, compression)
+		}
+		b, err = io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i+4 <= len(b); i += 4 {
+			tiles = append(tiles, binary.LittleEndian.Uint32(b[i:]))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s encoding", ErrUnsupported, encoding)
+	}
+	if len(tiles) != n {
+		return nil, fmt.Errorf("tilemap: layer has %d tiles, want %d", len(tiles), n)
+	}
+	return tiles, nil
+}
+
+// checkMap returns an error if the map is not an orthogonal, finite map.
+func checkMap(orientation string, infinite bool) error {
+	if orientation != "" && orientation != "orthogonal" {
+		return fmt.Errorf("%w: %s orientation", ErrUnsupported, orientation)
+	}
+	if infinite {
+		return fmt.Errorf("%w: infinite map", ErrUnsupported)
+	}
+	return nil
+}
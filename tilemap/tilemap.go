@@ -0,0 +1,344 @@
+// Package tilemap loads maps made with the Tiled map editor and draws them
+// with koebiten.
+//
+// Maps are loaded from the JSON (.tmj, .json) and TMX (.tmx) formats, with
+// embedded or external tilesets. Only orthogonal, finite maps are supported.
+package tilemap
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+)
+
+// ErrUnsupported is returned when a map uses a feature that is not supported.
+var ErrUnsupported = errors.New("tilemap: unsupported map")
+
+// The flags stored in the high bits of a global tile ID by Tiled.
+const (
+	FlippedHorizontally uint32 = 0x80000000
+	FlippedVertically   uint32 = 0x40000000
+	FlippedDiagonally   uint32 = 0x20000000
+
+	flipMask = FlippedHorizontally | FlippedVertically | FlippedDiagonally | 0x10000000
+)
+
+// Map is a map made of layers of tiles and layers of objects.
+type Map struct {
+	// Width and Height are the size of the map in tiles.
+	Width, Height int
+
+	// TileWidth and TileHeight are the size of a cell of the map in pixels.
+	TileWidth, TileHeight int
+
+	// Tilesets are the tilesets of the map, sorted by their first global tile
+	// ID.
+	Tilesets []*Tileset
+
+	// Layers are the tile layers, from the bottom to the top. The tile layers
+	// of groups are flattened into this list.
+	Layers []*Layer
+
+	// ObjectLayers are the object layers, from the bottom to the top.
+	ObjectLayers []*ObjectLayer
+
+	// Properties are the custom properties of the map.
+	Properties Properties
+}
+
+// New creates an empty map of width x height cells of tileWidth x
+// tileHeight pixels, to be filled by a program instead of loaded from a file.
+func New(width, height, tileWidth, tileHeight int) *Map {
+	return &Map{
+		Width:      width,
+		Height:     height,
+		TileWidth:  tileWidth,
+		TileHeight: tileHeight,
+		Properties: Properties{},
+	}
+}
+
+// AddTileset slices img into tiles of the size of the cells of the map and
+// adds them as a tileset. The global tile IDs of the tileset follow those of
+// the last tileset, starting at 1.
+func (m *Map) AddTileset(name string, img *koebiten.Image) *Tileset {
+	firstGID := uint32(1)
+	if n := len(m.Tilesets); n > 0 {
+		last := m.Tilesets[n-1]
+		firstGID = last.FirstGID + uint32(last.TileCount)
+	}
+	t := &Tileset{
+		FirstGID:   firstGID,
+		Name:       name,
+		TileWidth:  m.TileWidth,
+		TileHeight: m.TileHeight,
+	}
+	t.cut(img, 0, 0, 0)
+	m.Tilesets = append(m.Tilesets, t)
+	return t
+}
+
+// AddLayer adds an empty visible tile layer of the size of the map on top
+// of the other layers.
+func (m *Map) AddLayer(name string) *Layer {
+	l := &Layer{
+		Name:       name,
+		Visible:    true,
+		Width:      m.Width,
+		Height:     m.Height,
+		Tiles:      make([]uint32, m.Width*m.Height),
+		Properties: Properties{},
+	}
+	m.Layers = append(m.Layers, l)
+	return l
+}
+
+// Tileset is a set of tiles, either cut out of one image or made of one
+// image per tile.
+type Tileset struct {
+	// FirstGID is the global tile ID of the first tile of the tileset.
+	FirstGID uint32
+
+	Name string
+
+	// TileWidth and TileHeight are the size of the tiles in pixels.
+	TileWidth, TileHeight int
+
+	// TileCount is the number of tiles.
+	TileCount int
+
+	// Image is the image the tiles are cut out of, or nil if every tile has
+	// its own image.
+	Image *koebiten.Image
+
+	tiles map[int]*koebiten.Image
+}
+
+// Tile returns the image of the tile with the local ID id, or nil if there
+// is no such tile.
+func (t *Tileset) Tile(id int) *koebiten.Image {
+	return t.tiles[id]
+}
+
+// cut slices img into tiles, separated by spacing and surrounded by margin.
+func (t *Tileset) cut(img *koebiten.Image, margin, spacing, columns int) {
+	t.Image = img
+	t.tiles = map[int]*koebiten.Image{}
+	b := img.Bounds()
+	if columns <= 0 {
+		columns = (b.Dx() - 2*margin + spacing) / (t.TileWidth + spacing)
+	}
+	if columns <= 0 {
+		return
+	}
+	if t.TileCount <= 0 {
+		rows := (b.Dy() - 2*margin + spacing) / (t.TileHeight + spacing)
+		t.TileCount = columns * rows
+	}
+	for id := 0; id < t.TileCount; id++ {
+		x := margin + id%columns*(t.TileWidth+spacing)
+		y := margin + id/columns*(t.TileHeight+spacing)
+		r := image.Rect(x, y, x+t.TileWidth, y+t.TileHeight).Add(b.Min)
+		if !r.In(b) {
+			break
+		}
+		t.tiles[id] = img.SubImage(r)
+	}
+}
+
+// Layer is a layer of tiles.
+type Layer struct {
+	Name string
+
+	// Visible reports whether the layer is drawn by Map.Draw.
+	Visible bool
+
+	// OffsetX and OffsetY are the offset of the layer in pixels.
+	OffsetX, OffsetY float32
+
+	// Width and Height are the size of the layer in tiles.
+	Width, Height int
+
+	// Tiles are the global tile IDs of the cells, from left to right, then
+	// from top to bottom. 0 means an empty cell. The high bits of an ID are
+	// the flip flags.
+	Tiles []uint32
+
+	// Properties are the custom properties of the layer.
+	Properties Properties
+}
+
+// At returns the global tile ID at the cell (x, y) without the flip flags,
+// or 0 if the cell is empty or out of the layer.
+func (l *Layer) At(x, y int) uint32 {
+	if x < 0 || l.Width <= x || y < 0 || l.Height <= y {
+		return 0
+	}
+	return l.Tiles[y*l.Width+x] &^ flipMask
+}
+
+// Set sets the global tile ID at the cell (x, y). It does nothing if the
+// cell is out of the layer.
+func (l *Layer) Set(x, y int, gid uint32) {
+	if x < 0 || l.Width <= x || y < 0 || l.Height <= y {
+		return
+	}
+	l.Tiles[y*l.Width+x] = gid
+}
+
+// ObjectLayer is a layer of objects, such as spawn points and triggers.
+type ObjectLayer struct {
+	Name string
+
+	// Visible reports whether the layer is visible in Tiled.
+	Visible bool
+
+	// OffsetX and OffsetY are the offset of the layer in pixels.
+	OffsetX, OffsetY float32
+
+	Objects []*Object
+
+	// Properties are the custom properties of the layer.
+	Properties Properties
+}
+
+// Object returns the first object with the given name, or nil if there is
+// no such object.
+func (l *ObjectLayer) Object(name string) *Object {
+	for _, o := range l.Objects {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// Object is an object of an object layer.
+type Object struct {
+	ID   int
+	Name string
+
+	// Type is the class of the object.
+	Type string
+
+	// X and Y are the position of the object in pixels. For a tile object, it
+	// is the bottom-left corner of the tile.
+	X, Y float32
+
+	// Width and Height are the size of the object in pixels. They are 0 for
+	// a point.
+	Width, Height float32
+
+	// Rotation is the rotation of the object around its position, in degrees
+	// clockwise.
+	Rotation float32
+
+	// GID is the global tile ID of a tile object, or 0.
+	GID uint32
+
+	// Ellipse and Point report whether the object is an ellipse or a point.
+	Ellipse, Point bool
+
+	// Polygon and Polyline are the vertices of a polygon or a polyline,
+	// relative to the position of the object.
+	Polygon, Polyline []Point
+
+	// Properties are the custom properties of the object.
+	Properties Properties
+}
+
+// Bounds returns the rectangle of the object in pixels, rounded outward.
+// The rotation is ignored.
+func (o *Object) Bounds() image.Rectangle {
+	x, y := o.X, o.Y
+	if o.GID != 0 {
+		// A tile object is anchored at its bottom-left corner.
+		y -= o.Height
+	}
+	return image.Rect(
+		int(math32.Floor(x)), int(math32.Floor(y)),
+		int(math32.Ceil(x+o.Width)), int(math32.Ceil(y+o.Height)),
+	)
+}
+
+// Point is a point in pixels.
+type Point struct {
+	X, Y float32
+}
+
+// Properties are the custom properties of a map, a layer or an object.
+// The values are in the text form used by Tiled, such as "true" or "1.5".
+type Properties map[string]string
+
+// Get returns the value of the property name, or "" if there is no such
+// property.
+func (p Properties) Get(name string) string {
+	return p[name]
+}
+
+// Layer returns the tile layer with the given name, or nil if there is no
+// such layer.
+func (m *Map) Layer(name string) *Layer {
+	for _, l := range m.Layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// ObjectLayer returns the object layer with the given name, or nil if there
+// is no such layer.
+func (m *Map) ObjectLayer(name string) *ObjectLayer {
+	for _, l := range m.ObjectLayers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Tileset returns the tileset of the global tile ID gid, with or without the
+// flip flags, or nil if gid is 0 or out of the tilesets.
+func (m *Map) Tileset(gid uint32) *Tileset {
+	gid &^= flipMask
+	if gid == 0 {
+		return nil
+	}
+	for i := len(m.Tilesets) - 1; i >= 0; i-- {
+		if t := m.Tilesets[i]; t.FirstGID <= gid {
+			return t
+		}
+	}
+	return nil
+}
+
+// TileImage returns the image of the tile with the global tile ID gid, with
+// or without the flip flags, or nil if there is no such tile.
+func (m *Map) TileImage(gid uint32) *koebiten.Image {
+	t := m.Tileset(gid)
+	if t == nil {
+		return nil
+	}
+	return t.Tile(int(gid&^flipMask - t.FirstGID))
+}
+
+// Load loads the map at name in fsys. The format is chosen by the extension
+// of name: ".tmx" for TMX, and ".tmj" or ".json" for JSON. The external
+// tilesets and the images are loaded relative to the file that refers to
+// them, with koebiten.LoadImage.
+func Load(fsys fs.FS, name string) (*Map, error) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".tmx":
+		return loadTMX(fsys, name)
+	case ".tmj", ".json":
+		return loadJSON(fsys, name)
+	}
+	return nil, fmt.Errorf("%w: unknown format of %s", ErrUnsupported, name)
+}
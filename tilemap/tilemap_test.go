@@ -0,0 +1,245 @@
+package tilemap_test
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sago35/koebiten"
+	"github.com/sago35/koebiten/hardware"
+	"github.com/sago35/koebiten/tilemap"
+)
+
+var white = color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+
+// testFS returns a filesystem with a tileset image of two 4x4 tiles. The
+// first tile has its top-left pixel set and the second its top-right pixel.
+func testFS(t *testing.T) fstest.MapFS {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 4))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	// Dark pixels are set in a monochrome image.
+	img.Set(0, 0, color.Black)
+	img.Set(7, 0, color.Black)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return fstest.MapFS{"tiles/tiles.png": &fstest.MapFile{Data: buf.Bytes()}}
+}
+
+func TestLoadJSON(t *testing.T) {
+	fsys := testFS(t)
+	fsys["maps/level.tmj"] = &fstest.MapFile{Data: []byte(`{
+		"width": 3, "height": 2, "tilewidth": 4, "tileheight": 4,
+		"orientation": "orthogonal", "infinite": false,
+		"properties": [{"name": "music", "type": "string", "value": "forest"}],
+		"tilesets": [{"firstgid": 1, "name": "tiles", "tilewidth": 4, "tileheight": 4,
+			"tilecount": 2, "columns": 2, "image": "../tiles/tiles.png"}],
+		"layers": [
+			{"type": "tilelayer", "name": "ground", "width": 3, "height": 2, "visible": true,
+				"data": [1, 0, 2, 0, 2147483649, 0]},
+			{"type": "group", "name": "group", "offsetx": 4, "layers": [
+				{"type": "objectgroup", "name": "objects", "objects": [
+					{"id": 1, "name": "start", "type": "spawn", "x": 2, "y": 3, "width": 4, "height": 4,
+						"properties": [{"name": "hp", "type": "int", "value": 3}]}
+				]}
+			]}
+		]
+	}`)}
+
+	m, err := tilemap.Load(fsys, "maps/level.tmj")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Properties.Get("music"); got != "forest" {
+		t.Errorf("map property = %q, want forest", got)
+	}
+	l := m.Layer("ground")
+	if l == nil {
+		t.Fatal("Layer(ground) = nil")
+	}
+	if got := l.At(1, 1); got != 1 {
+		t.Errorf("At(1, 1) = %d, want 1 without the flip flags", got)
+	}
+
+	ol := m.ObjectLayer("objects")
+	if ol == nil {
+		t.Fatal("ObjectLayer(objects) = nil")
+	}
+	if ol.OffsetX != 4 {
+		t.Errorf("offset of the object layer = %v, want the offset of its group", ol.OffsetX)
+	}
+	o := ol.Object("start")
+	if o == nil || o.Type != "spawn" || o.Properties.Get("hp") != "3" {
+		t.Fatalf("Object(start) = %+v", o)
+	}
+	if got, want := o.Bounds(), image.Rect(2, 3, 6, 7); got != want {
+		t.Errorf("Bounds() = %v, want %v", got, want)
+	}
+
+	screen := koebiten.NewImage(8, 8)
+	m.Draw(screen, 4, 0)
+	for _, p := range []struct {
+		x, y int16
+		want bool
+	}{
+		{7, 0, true},  // the top-right pixel of the second tile at (2, 0)
+		{3, 4, true},  // the first tile at (1, 1), flipped horizontally
+		{0, 0, false}, // the first tile at (0, 0) is left of the screen
+	} {
+		if got := screen.PixelAt(p.x, p.y) == white; got != p.want {
+			t.Errorf("pixel (%d, %d) set = %v, want %v", p.x, p.y, got, p.want)
+		}
+	}
+}
+
+func TestLoadTMX(t *testing.T) {
+	fsys := testFS(t)
+	fsys["tiles/tiles.tsx"] = &fstest.MapFile{Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<tileset name="tiles" tilewidth="4" tileheight="4" tilecount="2" columns="2">
+ <image source="tiles.png" width="8" height="4"/>
+</tileset>`)}
+
+	// The second layer is compressed with zlib.
+	var raw bytes.Buffer
+	for _, gid := range []uint32{0, 2, 0, 1} {
+		binary.Write(&raw, binary.LittleEndian, gid)
+	}
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	zw.Write(raw.Bytes())
+	zw.Close()
+
+	fsys["level.tmx"] = &fstest.MapFile{Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="2" height="2" tilewidth="4" tileheight="4" infinite="0">
+ <tileset firstgid="1" source="tiles/tiles.tsx"/>
+ <layer name="ground" width="2" height="2">
+  <data encoding="csv">
+1,0,
+0,2
+</data>
+ </layer>
+ <objectgroup name="objects">
+  <object id="1" name="door" x="4" y="0" width="4" height="8"/>
+  <object id="2" name="path" x="0" y="0"><polyline points="0,0 4,4"/></object>
+ </objectgroup>
+ <group name="top">
+  <layer name="deco" width="2" height="2" visible="0">
+   <data encoding="base64" compression="zlib">` + base64.StdEncoding.EncodeToString(compressed.Bytes()) + `</data>
+  </layer>
+ </group>
+</map>`)}
+
+	m, err := tilemap.Load(fsys, "level.tmx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(m.Layers); got != 2 {
+		t.Fatalf("len(Layers) = %d, want 2", got)
+	}
+	if got := m.Layer("ground").At(1, 1); got != 2 {
+		t.Errorf("ground At(1, 1) = %d, want 2", got)
+	}
+	deco := m.Layer("deco")
+	if deco.Visible || deco.At(1, 0) != 2 || deco.At(1, 1) != 1 {
+		t.Errorf("deco = %+v", deco)
+	}
+	path := m.ObjectLayer("objects").Object("path")
+	if path == nil || len(path.Polyline) != 2 || path.Polyline[1] != (tilemap.Point{X: 4, Y: 4}) {
+		t.Errorf("Object(path) = %+v", path)
+	}
+
+	screen := koebiten.NewImage(8, 8)
+	m.Draw(screen, 0, 0)
+	if screen.PixelAt(0, 0) != white || screen.PixelAt(7, 4) != white {
+		t.Errorf("the ground layer is not drawn")
+	}
+	// The deco layer is not visible.
+	if screen.PixelAt(7, 0) == white {
+		t.Errorf("the hidden layer is drawn")
+	}
+}
+
+func TestNew(t *testing.T) {
+	tiles := koebiten.NewImage(8, 4)
+	tiles.SetPixel(4, 0, white)
+
+	m := tilemap.New(4, 4, 4, 4)
+	ts := m.AddTileset("tiles", tiles)
+	if ts.TileCount != 2 || ts.FirstGID != 1 {
+		t.Fatalf("tileset = %+v, want 2 tiles from 1", ts)
+	}
+	l := m.AddLayer("board")
+	l.Set(2, 3, 2)
+
+	screen := koebiten.NewImage(16, 16)
+	m.Draw(screen, 0, 0)
+	if got := screen.PixelAt(8, 12); got != white {
+		t.Errorf("pixel of the tile = %v, want white", got)
+	}
+}
+
+// mapGame draws a map to the screen passed to Draw.
+type mapGame struct {
+	m *tilemap.Map
+}
+
+func (g *mapGame) Update() error {
+	return nil
+}
+
+func (g *mapGame) Draw(screen *koebiten.Image) {
+	g.m.Draw(screen, 0, 0)
+}
+
+func (g *mapGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return outsideWidth, outsideHeight
+}
+
+func TestDrawToScreen(t *testing.T) {
+	dev := hardware.NewHeadless(16, 16)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+
+	tiles := koebiten.NewImage(8, 4)
+	tiles.SetPixel(4, 0, white)
+	m := tilemap.New(4, 4, 4, 4)
+	m.AddTileset("tiles", tiles)
+	m.AddLayer("board").Set(2, 3, 2)
+
+	if err := koebiten.RunGameWithOptions(&mapGame{m: m}, &koebiten.RunGameOptions{MaxFrames: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got := dev.Display().Image().RGBAAt(8, 12); got != white {
+		t.Errorf("pixel of the tile = %v, want white", got)
+	}
+}
+
+func TestLoadTMXTileCount(t *testing.T) {
+	fsys := testFS(t)
+	// The layer has 3 <tile> elements for 2x2 tiles.
+	fsys["level.tmx"] = &fstest.MapFile{Data: []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<map version="1.10" orientation="orthogonal" width="2" height="2" tilewidth="4" tileheight="4" infinite="0">
+ <layer name="ground" width="2" height="2">
+  <data>
+   <tile gid="1"/>
+   <tile/>
+   <tile gid="2"/>
+  </data>
+ </layer>
+</map>`)}
+
+	_, err := tilemap.Load(fsys, "level.tmx")
+	if err == nil || err.Error() != "tilemap: layer has 3 tiles, want 4" {
+		t.Errorf("got %v, want an error about the number of tiles", err)
+	}
+}
@@ -0,0 +1,256 @@
+package tilemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+type tmxMap struct {
+	Width       int           `xml:"width,attr"`
+	Height      int           `xml:"height,attr"`
+	TileWidth   int           `xml:"tilewidth,attr"`
+	TileHeight  int           `xml:"tileheight,attr"`
+	Orientation string        `xml:"orientation,attr"`
+	Infinite    int           `xml:"infinite,attr"`
+	Tilesets    []tmxTileset  `xml:"tileset"`
+	Properties  []tmxProperty `xml:"properties>property"`
+
+	// Layers, object groups and groups, in the order of the file.
+	Layers []tmxLayer `xml:",any"`
+}
+
+type tmxTileset struct {
+	FirstGID   uint32 `xml:"firstgid,attr"`
+	Source     string `xml:"source,attr"`
+	Name       string `xml:"name,attr"`
+	TileWidth  int    `xml:"tilewidth,attr"`
+	TileHeight int    `xml:"tileheight,attr"`
+	TileCount  int    `xml:"tilecount,attr"`
+	Columns    int    `xml:"columns,attr"`
+	Margin     int    `xml:"margin,attr"`
+	Spacing    int    `xml:"spacing,attr"`
+	Image      struct {
+		Source string `xml:"source,attr"`
+	} `xml:"image"`
+	Tiles []struct {
+		ID    int `xml:"id,attr"`
+		Image struct {
+			Source string `xml:"source,attr"`
+		} `xml:"image"`
+	} `xml:"tile"`
+}
+
+// tmxLayer is a layer, an object group or a group, told apart by XMLName.
+type tmxLayer struct {
+	XMLName    xml.Name
+	Name       string        `xml:"name,attr"`
+	Visible    string        `xml:"visible,attr"`
+	OffsetX    float32       `xml:"offsetx,attr"`
+	OffsetY    float32       `xml:"offsety,attr"`
+	Width      int           `xml:"width,attr"`
+	Height     int           `xml:"height,attr"`
+	Data       tmxData       `xml:"data"`
+	Objects    []tmxObject   `xml:"object"`
+	Properties []tmxProperty `xml:"properties>property"`
+	Layers     []tmxLayer    `xml:",any"`
+}
+
+type tmxData struct {
+	Encoding    string `xml:"encoding,attr"`
+	Compression string `xml:"compression,attr"`
+	Text        string `xml:",chardata"`
+	Tiles       []struct {
+		GID uint32 `xml:"gid,attr"`
+	} `xml:"tile"`
+}
+
+type tmxObject struct {
+	ID       int       `xml:"id,attr"`
+	Name     string    `xml:"name,attr"`
+	Type     string    `xml:"type,attr"`
+	Class    string    `xml:"class,attr"`
+	X        float32   `xml:"x,attr"`
+	Y        float32   `xml:"y,attr"`
+	Width    float32   `xml:"width,attr"`
+	Height   float32   `xml:"height,attr"`
+	Rotation float32   `xml:"rotation,attr"`
+	GID      uint32    `xml:"gid,attr"`
+	Ellipse  *struct{} `xml:"ellipse"`
+	Point    *struct{} `xml:"point"`
+	Polygon  *struct {
+		Points string `xml:"points,attr"`
+	} `xml:"polygon"`
+	Polyline *struct {
+		Points string `xml:"points,attr"`
+	} `xml:"polyline"`
+	Properties []tmxProperty `xml:"properties>property"`
+}
+
+type tmxProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+	Text  string `xml:",chardata"`
+}
+
+func loadTMX(fsys fs.FS, name string) (*Map, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	var tm tmxMap
+	if err := xml.Unmarshal(data, &tm); err != nil {
+		return nil, err
+	}
+	if err := checkMap(tm.Orientation, tm.Infinite != 0); err != nil {
+		return nil, err
+	}
+
+	m := &Map{
+		Width:      tm.Width,
+		Height:     tm.Height,
+		TileWidth:  tm.TileWidth,
+		TileHeight: tm.TileHeight,
+		Properties: tmxProperties(tm.Properties),
+	}
+	for _, tt := range tm.Tilesets {
+		file, src := name, tt.source()
+		if tt.Source != "" {
+			file = path.Join(path.Dir(name), tt.Source)
+			if src, err = readTilesetFile(fsys, file); err != nil {
+				return nil, err
+			}
+		}
+		if err := m.addTileset(fsys, file, tt.FirstGID, src); err != nil {
+			return nil, err
+		}
+	}
+	if err := m.addTMXLayers(tm.Layers, group{visible: true}); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (tt *tmxTileset) source() *tilesetSource {
+	src := &tilesetSource{
+		name:       tt.Name,
+		tileWidth:  tt.TileWidth,
+		tileHeight: tt.TileHeight,
+		tileCount:  tt.TileCount,
+		columns:    tt.Columns,
+		margin:     tt.Margin,
+		spacing:    tt.Spacing,
+		image:      tt.Image.Source,
+		tileImages: map[int]string{},
+	}
+	for _, t := range tt.Tiles {
+		if t.Image.Source != "" {
+			src.tileImages[t.ID] = t.Image.Source
+		}
+	}
+	return src
+}
+
+func (m *Map) addTMXLayers(layers []tmxLayer, g group) error {
+	for _, tl := range layers {
+		visible := g.visible && tl.Visible != "0"
+		offsetX, offsetY := g.offsetX+tl.OffsetX, g.offsetY+tl.OffsetY
+		switch tl.XMLName.Local {
+		case "layer":
+			n := tl.Width * tl.Height
+			var tiles []uint32
+			if tl.Data.Encoding == "" {
+				if len(tl.Data.Tiles) != n {
+					return fmt.Errorf("tilemap: layer has %d tiles, want %d", len(tl.Data.Tiles), n)
+				}
+				tiles = make([]uint32, n)
+				for i, t := range tl.Data.Tiles {
+					tiles[i] = t.GID
+				}
+			} else {
+				var err error
+				if tiles, err = decodeTiles(tl.Data.Text, tl.Data.Encoding, tl.Data.Compression, n); err != nil {
+					return err
+				}
+			}
+			m.Layers = append(m.Layers, &Layer{
+				Name:       tl.Name,
+				Visible:    visible,
+				OffsetX:    offsetX,
+				OffsetY:    offsetY,
+				Width:      tl.Width,
+				Height:     tl.Height,
+				Tiles:      tiles,
+				Properties: tmxProperties(tl.Properties),
+			})
+		case "objectgroup":
+			l := &ObjectLayer{
+				Name:       tl.Name,
+				Visible:    visible,
+				OffsetX:    offsetX,
+				OffsetY:    offsetY,
+				Properties: tmxProperties(tl.Properties),
+			}
+			for _, to := range tl.Objects {
+				o := &Object{
+					ID:         to.ID,
+					Name:       to.Name,
+					Type:       to.Type,
+					X:          to.X,
+					Y:          to.Y,
+					Width:      to.Width,
+					Height:     to.Height,
+					Rotation:   to.Rotation,
+					GID:        to.GID,
+					Ellipse:    to.Ellipse != nil,
+					Point:      to.Point != nil,
+					Properties: tmxProperties(to.Properties),
+				}
+				if o.Type == "" {
+					o.Type = to.Class
+				}
+				if to.Polygon != nil {
+					o.Polygon = tmxPoints(to.Polygon.Points)
+				}
+				if to.Polyline != nil {
+					o.Polyline = tmxPoints(to.Polyline.Points)
+				}
+				l.Objects = append(l.Objects, o)
+			}
+			m.ObjectLayers = append(m.ObjectLayers, l)
+		case "group":
+			if err := m.addTMXLayers(tl.Layers, group{visible: visible, offsetX: offsetX, offsetY: offsetY}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// tmxPoints parses points in the form "x1,y1 x2,y2 ...".
+func tmxPoints(s string) []Point {
+	var points []Point
+	for _, f := range strings.Fields(s) {
+		x, y, _ := strings.Cut(f, ",")
+		px, _ := strconv.ParseFloat(x, 32)
+		py, _ := strconv.ParseFloat(y, 32)
+		points = append(points, Point{X: float32(px), Y: float32(py)})
+	}
+	return points
+}
+
+func tmxProperties(props []tmxProperty) Properties {
+	p := Properties{}
+	for _, prop := range props {
+		v := prop.Value
+		if v == "" {
+			// Multi-line strings are stored as the text of the property.
+			v = prop.Text
+		}
+		p[prop.Name] = v
+	}
+	return p
+}
@@ -0,0 +1,138 @@
+package koebiten
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTPS represents a default ticks per second, that represents how many times game updating happens in a second.
+	DefaultTPS = 60
+
+	// SyncWithFPS is a special TPS value that means the game doesn't wait between ticks and updates as fast as it can.
+	SyncWithFPS = -1
+
+	// DefaultMaxFrameSkip is the default number of frames that can be skipped in a row to catch up with the TPS.
+	DefaultMaxFrameSkip = 4
+)
+
+// now returns the current time. It is a variable so that tests can replace the clock.
+var now = time.Now
+
+var theClock = &clock{
+	tps:       DefaultTPS,
+	frameSkip: DefaultMaxFrameSkip,
+}
+
+type clock struct {
+	tps       int
+	frameSkip int
+
+	// times is a ring buffer of the start times of the latest ticks in microseconds.
+	times [32]int64
+	n     int
+
+	m sync.RWMutex
+}
+
+// SetTPS sets the maximum TPS (ticks per second), that represents how many updating function is called per second.
+// The initial value is 60.
+//
+// If tps is SyncWithFPS, the game is updated as fast as possible.
+// If tps is less than 1 and not SyncWithFPS, SetTPS panics.
+//
+// SetTPS is concurrent safe.
+func SetTPS(tps int) {
+	if tps < 1 && tps != SyncWithFPS {
+		panic("koebiten: tps must be >= 1 or SyncWithFPS")
+	}
+	theClock.m.Lock()
+	theClock.tps = tps
+	theClock.n = 0
+	theClock.m.Unlock()
+}
+
+// TPS returns a current TPS (ticks per second), that represents how many update function is called in a second.
+//
+// TPS is concurrent safe.
+func TPS() int {
+	theClock.m.RLock()
+	defer theClock.m.RUnlock()
+	return theClock.tps
+}
+
+// ActualTPS returns the current TPS (ticks per second), that represents how many update function is called in a second.
+// The value is measured over the latest 32 ticks.
+//
+// ActualTPS is concurrent safe.
+func ActualTPS() float64 {
+	return theClock.actualTPS()
+}
+
+func (c *clock) actualTPS() float64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	n := c.n
+	if n > len(c.times) {
+		n = len(c.times)
+	}
+	if n < 2 {
+		return 0
+	}
+	newest := c.times[(c.n-1)%len(c.times)]
+	oldest := c.times[(c.n-n)%len(c.times)]
+	if newest <= oldest {
+		return 0
+	}
+	return float64(n-1) * 1e6 / float64(newest-oldest)
+}
+
+// SetMaxFrameSkip sets how many frames can be skipped in a row when the game
+// falls behind the TPS. Skipping a frame means Update is called but Draw and
+// Display are not. The initial value is DefaultMaxFrameSkip.
+//
+// If n is 0, every Update is followed by Draw and the game slows down instead.
+// If n is negative, SetMaxFrameSkip panics.
+//
+// SetMaxFrameSkip is concurrent safe.
+func SetMaxFrameSkip(n int) {
+	if n < 0 {
+		panic("koebiten: max frame skip must be >= 0")
+	}
+	theClock.m.Lock()
+	theClock.frameSkip = n
+	theClock.m.Unlock()
+}
+
+// MaxFrameSkip returns the current maximum number of frames skipped in a row.
+//
+// MaxFrameSkip is concurrent safe.
+func MaxFrameSkip() int {
+	return theClock.maxFrameSkip()
+}
+
+func (c *clock) maxFrameSkip() int {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	return c.frameSkip
+}
+
+// tickDuration returns the interval between two ticks for the current TPS.
+// It returns 0 if the TPS is SyncWithFPS.
+func (c *clock) tickDuration() time.Duration {
+	c.m.RLock()
+	defer c.m.RUnlock()
+	if c.tps == SyncWithFPS {
+		return 0
+	}
+	return time.Second / time.Duration(c.tps)
+}
+
+// tick records the start of a tick.
+func (c *clock) tick(t time.Time) {
+	c.m.Lock()
+	c.times[c.n%len(c.times)] = t.UnixMicro()
+	c.n++
+	c.m.Unlock()
+}
@@ -0,0 +1,54 @@
+package koebiten
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActualTPS(t *testing.T) {
+	c := &clock{tps: DefaultTPS}
+	if g, e := c.actualTPS(), 0.0; g != e {
+		t.Errorf("got %f want %f", g, e)
+	}
+
+	base := time.Unix(0, 0)
+	for i := 0; i < 10; i++ {
+		c.tick(base.Add(time.Duration(i) * 20 * time.Millisecond))
+	}
+	if g, e := c.actualTPS(), 50.0; g != e {
+		t.Errorf("got %f want %f", g, e)
+	}
+
+	// Only the latest ticks are taken into account.
+	last := base.Add(9 * 20 * time.Millisecond)
+	for i := 1; i <= 64; i++ {
+		c.tick(last.Add(time.Duration(i) * 10 * time.Millisecond))
+	}
+	if g, e := c.actualTPS(), 100.0; g != e {
+		t.Errorf("got %f want %f", g, e)
+	}
+}
+
+func TestSetTPS(t *testing.T) {
+	defer SetTPS(TPS())
+
+	SetTPS(30)
+	if g, e := TPS(), 30; g != e {
+		t.Errorf("got %d want %d", g, e)
+	}
+	if g, e := theClock.tickDuration(), time.Second/30; g != e {
+		t.Errorf("got %v want %v", g, e)
+	}
+
+	SetTPS(SyncWithFPS)
+	if g, e := theClock.tickDuration(), time.Duration(0); g != e {
+		t.Errorf("got %v want %v", g, e)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SetTPS(0) must panic")
+		}
+	}()
+	SetTPS(0)
+}
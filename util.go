@@ -26,8 +26,9 @@ type RotatedDisplay struct {
 }
 
 func (d *RotatedDisplay) Size() (x, y int16) {
+	x, y = d.Displayer.Size()
 	switch d.mode {
-	case 0, 2:
+	case Rotation0, Rotation180:
 		return x, y
 	default:
 	}
@@ -35,19 +36,34 @@ func (d *RotatedDisplay) Size() (x, y int16) {
 }
 
 func (d *RotatedDisplay) SetPixel(x, y int16, c color.RGBA) {
+	x, y = d.toDisplay(x, y)
+	d.Displayer.SetPixel(x, y, c)
+}
+
+// PixelAt returns the color of the pixel at the given x and y coordinates.
+// It returns black if the wrapped display doesn't implement PixelReader.
+func (d *RotatedDisplay) PixelAt(x, y int16) color.RGBA {
+	r, ok := d.Displayer.(PixelReader)
+	if !ok {
+		return black
+	}
+	return r.PixelAt(d.toDisplay(x, y))
+}
+
+// toDisplay converts the rotated coordinates to the coordinates of the wrapped display.
+func (d *RotatedDisplay) toDisplay(x, y int16) (int16, int16) {
 	switch d.mode {
-	case Rotation0:
-		d.Displayer.SetPixel(x, y, c)
 	case Rotation90:
 		sx, _ := d.Displayer.Size()
-		d.Displayer.SetPixel(sx-y, x, c)
+		return sx - 1 - y, x
 	case Rotation180:
 		sx, sy := d.Displayer.Size()
-		d.Displayer.SetPixel(sx-x, sy-y, c)
+		return sx - 1 - x, sy - 1 - y
 	case Rotation270:
 		_, sy := d.Displayer.Size()
-		d.Displayer.SetPixel(y, sy-x, c)
+		return y, sy - 1 - x
 	}
+	return x, y
 }
 
 // Clockwise rotation of the screen.
@@ -69,6 +85,6 @@ func (g dummyGame) Draw(*Image) {
 	g()
 }
 
-func (g dummyGame) Layout(int, int) (int, int) {
-	return 128, 64
+func (g dummyGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return outsideWidth, outsideHeight
 }
@@ -29,3 +29,25 @@ func TestC565toRGBA(t *testing.T) {
 		t.Errorf("got %04X want %04X", g, e)
 	}
 }
+
+func TestRotatedDisplayCorners(t *testing.T) {
+	white := color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}
+	for _, mode := range []int{Rotation0, Rotation90, Rotation180, Rotation270} {
+		img := NewImage(8, 4)
+		d := &RotatedDisplay{Displayer: img, mode: mode}
+		w, h := d.Size()
+		for _, p := range [][2]int16{{0, 0}, {w - 1, 0}, {0, h - 1}, {w - 1, h - 1}} {
+			d.SetPixel(p[0], p[1], white)
+			if got := d.PixelAt(p[0], p[1]); got != white {
+				t.Errorf("mode %d: pixel %v = %v, want white", mode, p, got)
+			}
+		}
+		// The four corners of the rotated display are the four corners of
+		// the panel.
+		for _, p := range [][2]int16{{0, 0}, {7, 0}, {0, 3}, {7, 3}} {
+			if got := img.PixelAt(p[0], p[1]); got != white {
+				t.Errorf("mode %d: corner %v of the panel = %v, want white", mode, p, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package vector
+
+import (
+	"image/color"
+	"slices"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers/pixel"
+)
+
+// tolerance is the maximum distance in pixels between a curve and the lines
+// that approximate it.
+const tolerance = 0.25
+
+// FillRule represents the rule that decides which areas of a path are
+// filled.
+type FillRule int
+
+const (
+	// FillRuleNonZero fills the areas that the subpaths wind around a
+	// non-zero number of times, counting the clockwise turns as +1 and the
+	// counterclockwise ones as -1.
+	FillRuleNonZero FillRule = iota
+
+	// FillRuleEvenOdd fills the areas that the subpaths wind around an odd
+	// number of times.
+	FillRuleEvenOdd
+)
+
+// FillOptions represents options for Path.Fill.
+type FillOptions struct {
+	// FillRule is the rule that decides which areas are filled.
+	// The default (zero) value is FillRuleNonZero.
+	FillRule FillRule
+
+	// GeoM is the geometry matrix from the path to the destination.
+	// The default (zero) value is identity.
+	GeoM koebiten.GeoM
+}
+
+// Fill fills the inside of the path on dst with c. Every subpath is closed
+// for filling. If dst is nil, the path is filled on the screen.
+func (p *Path) Fill(dst koebiten.Displayer, c pixel.BaseColor, options FillOptions) {
+	dst = koebiten.DisplayerOrScreen(dst)
+	if dst == nil {
+		return
+	}
+	s := scale(&options.GeoM)
+	if s == 0 {
+		return
+	}
+	var polys [][]point
+	for _, l := range p.flatten(tolerance / s) {
+		polys = append(polys, l.points)
+	}
+	fill(dst, polys, &options.GeoM, options.FillRule, c.RGBA())
+}
+
+// scale returns how much g stretches the lengths at most, roughly.
+func scale(g *koebiten.GeoM) float32 {
+	a, b := g.Element(0, 0), g.Element(0, 1)
+	c, d := g.Element(1, 0), g.Element(1, 1)
+	return math32.Sqrt(max(a*a+c*c, b*b+d*d))
+}
+
+type edge struct {
+	x0, y0, x1, y1 float32
+	dir            int
+}
+
+type crossing struct {
+	x   float32
+	dir int
+}
+
+// fill fills the polygons transformed by g on dst with rule. A pixel is drawn
+// when its center is inside.
+func fill(dst koebiten.Displayer, polys [][]point, g *koebiten.GeoM, rule FillRule, c color.RGBA) {
+	var edges []edge
+	minY, maxY := math32.Inf(1), math32.Inf(-1)
+	for _, poly := range polys {
+		if len(poly) < 3 {
+			continue
+		}
+		for i := range poly {
+			x0, y0 := g.Apply(poly[i].x, poly[i].y)
+			x1, y1 := g.Apply(poly[(i+1)%len(poly)].x, poly[(i+1)%len(poly)].y)
+			if y0 == y1 {
+				continue
+			}
+			e := edge{x0, y0, x1, y1, 1}
+			if y0 > y1 {
+				e = edge{x1, y1, x0, y0, -1}
+			}
+			edges = append(edges, e)
+			minY, maxY = math32.Min(minY, e.y0), math32.Max(maxY, e.y1)
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+
+	w, h := dst.Size()
+	var xs []crossing
+	for y := max(int(math32.Floor(minY)), 0); y < min(int(math32.Ceil(maxY)), int(h)); y++ {
+		fy := float32(y) + 0.5
+		xs = xs[:0]
+		for _, e := range edges {
+			if fy < e.y0 || fy >= e.y1 {
+				continue
+			}
+			xs = append(xs, crossing{e.x0 + (fy-e.y0)*(e.x1-e.x0)/(e.y1-e.y0), e.dir})
+		}
+		slices.SortFunc(xs, func(a, b crossing) int {
+			switch {
+			case a.x < b.x:
+				return -1
+			case a.x > b.x:
+				return 1
+			}
+			return 0
+		})
+
+		winding := 0
+		for i := 0; i+1 < len(xs); i++ {
+			winding += xs[i].dir
+			if rule == FillRuleEvenOdd && winding%2 == 0 || winding == 0 {
+				continue
+			}
+			// The pixels whose center is in [xs[i].x, xs[i+1].x).
+			x0 := max(int(math32.Ceil(xs[i].x-0.5)), 0)
+			x1 := min(int(math32.Ceil(xs[i+1].x-0.5)), int(w))
+			for x := x0; x < x1; x++ {
+				dst.SetPixel(int16(x), int16(y), c)
+			}
+		}
+	}
+}
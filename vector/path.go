@@ -0,0 +1,219 @@
+// Package vector draws shapes made of lines and curves to koebiten
+// Displayers.
+//
+// Coordinates are continuous: the pixel (x, y) covers the square from
+// (x, y) to (x+1, y+1), and a pixel is drawn when its center is in the shape.
+// Angles are in radians, and clockwise on the display as the y axis points
+// down.
+package vector
+
+import (
+	"github.com/chewxy/math32"
+)
+
+// Direction represents the direction of an arc.
+type Direction int
+
+const (
+	// Clockwise draws an arc clockwise on the display, from the start angle
+	// to a greater end angle.
+	Clockwise Direction = iota
+
+	// CounterClockwise draws an arc counterclockwise on the display.
+	CounterClockwise
+)
+
+type point struct {
+	x, y float32
+}
+
+// segment is a line, a quadratic Bézier curve or a cubic Bézier curve from
+// the end of the previous segment. n is the number of points used in p.
+type segment struct {
+	n int
+	p [3]point
+}
+
+type subpath struct {
+	start  point
+	segs   []segment
+	closed bool
+}
+
+// end returns the current point of the subpath.
+func (s *subpath) end() point {
+	if len(s.segs) == 0 {
+		return s.start
+	}
+	seg := s.segs[len(s.segs)-1]
+	return seg.p[seg.n-1]
+}
+
+// Path represents a collection of subpaths made of lines and curves.
+//
+// The zero value is an empty path ready to use.
+type Path struct {
+	subpaths []subpath
+}
+
+// MoveTo starts a new subpath at (x, y).
+func (p *Path) MoveTo(x, y float32) {
+	p.subpaths = append(p.subpaths, subpath{start: point{x, y}})
+}
+
+// LineTo adds a line from the current point to (x, y). If the path has no
+// current point, LineTo is the same as MoveTo.
+func (p *Path) LineTo(x, y float32) {
+	p.add(segment{n: 1, p: [3]point{{x, y}}})
+}
+
+// QuadTo adds a quadratic Bézier curve from the current point to (x2, y2)
+// with the control point (x1, y1).
+func (p *Path) QuadTo(x1, y1, x2, y2 float32) {
+	p.add(segment{n: 2, p: [3]point{{x1, y1}, {x2, y2}}})
+}
+
+// CubicTo adds a cubic Bézier curve from the current point to (x3, y3) with
+// the control points (x1, y1) and (x2, y2).
+func (p *Path) CubicTo(x1, y1, x2, y2, x3, y3 float32) {
+	p.add(segment{n: 3, p: [3]point{{x1, y1}, {x2, y2}, {x3, y3}}})
+}
+
+// Arc adds an arc of the circle centered on (x, y) from startAngle to
+// endAngle in the direction dir. A line is added from the current point to
+// the start of the arc, or a new subpath is started there if the path has no
+// current point.
+func (p *Path) Arc(x, y, radius, startAngle, endAngle float32, dir Direction) {
+	const twoPi = 2 * math32.Pi
+	if dir == Clockwise {
+		for endAngle < startAngle {
+			endAngle += twoPi
+		}
+	} else {
+		for endAngle > startAngle {
+			endAngle -= twoPi
+		}
+	}
+
+	sx, sy := x+radius*math32.Cos(startAngle), y+radius*math32.Sin(startAngle)
+	if len(p.subpaths) == 0 {
+		p.MoveTo(sx, sy)
+	} else {
+		p.LineTo(sx, sy)
+	}
+
+	// Each part of at most a quarter of the circle is approximated by a cubic
+	// Bézier curve.
+	sweep := endAngle - startAngle
+	n := max(int(math32.Ceil(math32.Abs(sweep)/(math32.Pi/2))), 1)
+	step := sweep / float32(n)
+	k := 4.0 / 3 * math32.Tan(step/4) * radius
+	a := startAngle
+	for i := 0; i < n; i++ {
+		b := a + step
+		cosA, sinA := math32.Cos(a), math32.Sin(a)
+		cosB, sinB := math32.Cos(b), math32.Sin(b)
+		p.CubicTo(
+			x+radius*cosA-k*sinA, y+radius*sinA+k*cosA,
+			x+radius*cosB+k*sinB, y+radius*sinB-k*cosB,
+			x+radius*cosB, y+radius*sinB)
+		a = b
+	}
+}
+
+// Close closes the current subpath with a line to its start point. The next
+// segment starts a new subpath at the same point.
+func (p *Path) Close() {
+	if len(p.subpaths) == 0 {
+		return
+	}
+	p.subpaths[len(p.subpaths)-1].closed = true
+}
+
+func (p *Path) add(seg segment) {
+	if len(p.subpaths) == 0 {
+		last := seg.p[seg.n-1]
+		p.MoveTo(last.x, last.y)
+		return
+	}
+	s := &p.subpaths[len(p.subpaths)-1]
+	if s.closed {
+		p.MoveTo(s.start.x, s.start.y)
+		s = &p.subpaths[len(p.subpaths)-1]
+	}
+	s.segs = append(s.segs, seg)
+}
+
+// polyline is a flattened subpath.
+type polyline struct {
+	points []point
+	closed bool
+}
+
+// flatten approximates the subpaths by lines whose distance to the curves is
+// at most tolerance. Subpaths without segments are skipped, and the points
+// of a polyline are all different from the previous one.
+func (p *Path) flatten(tolerance float32) []polyline {
+	var lines []polyline
+	for _, s := range p.subpaths {
+		if len(s.segs) == 0 {
+			continue
+		}
+		pts := []point{s.start}
+		cur := s.start
+		for _, seg := range s.segs {
+			switch seg.n {
+			case 1:
+				pts = append(pts, seg.p[0])
+			case 2:
+				p0, p1, p2 := cur, seg.p[0], seg.p[1]
+				dd := math32.Hypot(p0.x-2*p1.x+p2.x, p0.y-2*p1.y+p2.y)
+				n := segments(dd/4, tolerance)
+				for i := 1; i <= n; i++ {
+					t := float32(i) / float32(n)
+					u := 1 - t
+					pts = append(pts, point{
+						u*u*p0.x + 2*u*t*p1.x + t*t*p2.x,
+						u*u*p0.y + 2*u*t*p1.y + t*t*p2.y,
+					})
+				}
+			case 3:
+				p0, p1, p2, p3 := cur, seg.p[0], seg.p[1], seg.p[2]
+				dd := max(
+					math32.Hypot(p0.x-2*p1.x+p2.x, p0.y-2*p1.y+p2.y),
+					math32.Hypot(p1.x-2*p2.x+p3.x, p1.y-2*p2.y+p3.y))
+				n := segments(dd*3/4, tolerance)
+				for i := 1; i <= n; i++ {
+					t := float32(i) / float32(n)
+					u := 1 - t
+					pts = append(pts, point{
+						u*u*u*p0.x + 3*u*u*t*p1.x + 3*u*t*t*p2.x + t*t*t*p3.x,
+						u*u*u*p0.y + 3*u*u*t*p1.y + 3*u*t*t*p2.y + t*t*t*p3.y,
+					})
+				}
+			}
+			cur = seg.p[seg.n-1]
+		}
+
+		// Remove the repeated points.
+		out := pts[:1]
+		for _, pt := range pts[1:] {
+			if pt != out[len(out)-1] {
+				out = append(out, pt)
+			}
+		}
+		if s.closed && len(out) > 1 && out[0] == out[len(out)-1] {
+			out = out[:len(out)-1]
+		}
+		lines = append(lines, polyline{points: out, closed: s.closed})
+	}
+	return lines
+}
+
+// segments returns the number of lines that approximate a curve within
+// tolerance, where e is the error of a single line times the square of the
+// number of lines.
+func segments(e, tolerance float32) int {
+	n := int(math32.Ceil(math32.Sqrt(e / tolerance)))
+	return min(max(n, 1), 100)
+}
@@ -0,0 +1,229 @@
+package vector
+
+import (
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+	"tinygo.org/x/drivers/pixel"
+)
+
+// LineJoin represents the shape of the corners of a stroke.
+type LineJoin int
+
+const (
+	// LineJoinMiter extends the outer edges of the lines until they meet.
+	// Sharp corners longer than the miter limit are beveled.
+	LineJoinMiter LineJoin = iota
+
+	// LineJoinBevel cuts the corners with a straight line.
+	LineJoinBevel
+
+	// LineJoinRound rounds the corners.
+	LineJoinRound
+)
+
+// LineCap represents the shape of the ends of an open subpath.
+type LineCap int
+
+const (
+	// LineCapButt ends the stroke at the end points.
+	LineCapButt LineCap = iota
+
+	// LineCapRound adds a half circle at the ends.
+	LineCapRound
+
+	// LineCapSquare extends the stroke by half the width at the ends.
+	LineCapSquare
+)
+
+// StrokeOptions represents options for Path.Stroke.
+type StrokeOptions struct {
+	// Width is the width of the stroke in the coordinates of the path.
+	// The default (zero) value is 0, which means 1.
+	Width float32
+
+	// LineJoin is the shape of the corners.
+	// The default (zero) value is LineJoinMiter.
+	LineJoin LineJoin
+
+	// LineCap is the shape of the ends of the open subpaths.
+	// The default (zero) value is LineCapButt.
+	LineCap LineCap
+
+	// MiterLimit is the maximum ratio of the length of a miter to the width,
+	// above which the corner is beveled.
+	// The default (zero) value is 0, which means 4.
+	MiterLimit float32
+
+	// GeoM is the geometry matrix from the path to the destination. The
+	// stroke is transformed with the path, so a scale changes its width too.
+	// The default (zero) value is identity.
+	GeoM koebiten.GeoM
+}
+
+// Stroke draws the outline of the path on dst with c. If dst is nil, the
+// outline is drawn on the screen.
+func (p *Path) Stroke(dst koebiten.Displayer, c pixel.BaseColor, options StrokeOptions) {
+	dst = koebiten.DisplayerOrScreen(dst)
+	if dst == nil {
+		return
+	}
+	s := scale(&options.GeoM)
+	if s == 0 {
+		return
+	}
+	width := options.Width
+	if width <= 0 {
+		width = 1
+	}
+	miterLimit := options.MiterLimit
+	if miterLimit <= 0 {
+		miterLimit = 4
+	}
+	st := stroker{
+		hw:         width / 2,
+		join:       options.LineJoin,
+		cap:        options.LineCap,
+		miterLimit: miterLimit,
+		tolerance:  tolerance / s,
+	}
+	for _, l := range p.flatten(st.tolerance) {
+		st.stroke(l)
+	}
+	// All the parts have the same orientation, so they are merged by the
+	// nonzero rule.
+	fill(dst, st.polys, &options.GeoM, FillRuleNonZero, c.RGBA())
+}
+
+// stroker builds the outline of a stroke as convex polygons.
+type stroker struct {
+	hw         float32
+	join       LineJoin
+	cap        LineCap
+	miterLimit float32
+	tolerance  float32
+
+	polys [][]point
+}
+
+func (st *stroker) stroke(l polyline) {
+	pts := l.points
+	if len(pts) == 1 {
+		// A subpath of zero length only has its caps.
+		switch st.cap {
+		case LineCapRound:
+			st.circle(pts[0])
+		case LineCapSquare:
+			p, hw := pts[0], st.hw
+			st.add(point{p.x - hw, p.y - hw}, point{p.x + hw, p.y - hw}, point{p.x + hw, p.y + hw}, point{p.x - hw, p.y + hw})
+		}
+		return
+	}
+
+	n := len(pts) - 1
+	if l.closed {
+		n = len(pts)
+	}
+	for i := 0; i < n; i++ {
+		a, b := pts[i], pts[(i+1)%len(pts)]
+		nx, ny := normal(a, b)
+		nx, ny = nx*st.hw, ny*st.hw
+		st.add(point{a.x + nx, a.y + ny}, point{b.x + nx, b.y + ny}, point{b.x - nx, b.y - ny}, point{a.x - nx, a.y - ny})
+	}
+
+	if l.closed {
+		for i := range pts {
+			st.joinAt(pts[(i+len(pts)-1)%len(pts)], pts[i], pts[(i+1)%len(pts)])
+		}
+		return
+	}
+	for i := 1; i < len(pts)-1; i++ {
+		st.joinAt(pts[i-1], pts[i], pts[i+1])
+	}
+	st.capAt(pts[1], pts[0])
+	st.capAt(pts[len(pts)-2], pts[len(pts)-1])
+}
+
+// joinAt adds the corner at b between the lines a-b and b-c.
+func (st *stroker) joinAt(a, b, c point) {
+	if st.join == LineJoinRound {
+		st.circle(b)
+		return
+	}
+	n0x, n0y := normal(a, b)
+	n1x, n1y := normal(b, c)
+	// Use the normals on the outer side of the corner.
+	if n0x*(c.x-b.x)+n0y*(c.y-b.y) > 0 {
+		n0x, n0y, n1x, n1y = -n0x, -n0y, -n1x, -n1y
+	}
+	p0 := point{b.x + n0x*st.hw, b.y + n0y*st.hw}
+	p1 := point{b.x + n1x*st.hw, b.y + n1y*st.hw}
+	if st.join == LineJoinMiter {
+		mx, my := n0x+n1x, n0y+n1y
+		if l := math32.Hypot(mx, my); l > 0 {
+			mx, my = mx/l, my/l
+			// cos is the cosine of half the angle between the normals, and
+			// 1/cos is the ratio of the miter length to the width.
+			if cos := mx*n0x + my*n0y; cos > 0 && 1/cos <= st.miterLimit {
+				m := point{b.x + mx*st.hw/cos, b.y + my*st.hw/cos}
+				st.add(b, p0, m, p1)
+				return
+			}
+		}
+	}
+	st.add(b, p0, p1)
+}
+
+// capAt adds the cap at the end b of the line a-b.
+func (st *stroker) capAt(a, b point) {
+	switch st.cap {
+	case LineCapRound:
+		st.circle(b)
+	case LineCapSquare:
+		nx, ny := normal(a, b)
+		nx, ny = nx*st.hw, ny*st.hw
+		// The direction of the line, half the width long.
+		dx, dy := -ny, nx
+		st.add(point{b.x + nx, b.y + ny}, point{b.x + dx + nx, b.y + dy + ny}, point{b.x + dx - nx, b.y + dy - ny}, point{b.x - nx, b.y - ny})
+	}
+}
+
+// circle adds a circle of the width of the stroke centered on c.
+func (st *stroker) circle(c point) {
+	n := 8
+	if st.hw > st.tolerance {
+		n = max(int(math32.Ceil(math32.Pi/math32.Acos(1-st.tolerance/st.hw))), n)
+	}
+	n = min(n, 64)
+	pts := make([]point, n)
+	for i := range pts {
+		a := 2 * math32.Pi * float32(i) / float32(n)
+		pts[i] = point{c.x + st.hw*math32.Cos(a), c.y + st.hw*math32.Sin(a)}
+	}
+	st.add(pts...)
+}
+
+// add adds a convex polygon, turned in the same direction as the others.
+func (st *stroker) add(pts ...point) {
+	area := float32(0)
+	for i, a := range pts {
+		b := pts[(i+1)%len(pts)]
+		area += a.x*b.y - b.x*a.y
+	}
+	if area == 0 {
+		return
+	}
+	if area < 0 {
+		for i, j := 0, len(pts)-1; i < j; i, j = i+1, j-1 {
+			pts[i], pts[j] = pts[j], pts[i]
+		}
+	}
+	st.polys = append(st.polys, pts)
+}
+
+// normal returns the unit normal of the line a-b, on the left of the
+// direction from a to b on the display.
+func normal(a, b point) (float32, float32) {
+	dx, dy := b.x-a.x, b.y-a.y
+	l := math32.Hypot(dx, dy)
+	return dy / l, -dx / l
+}
@@ -0,0 +1,192 @@
+package vector_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/chewxy/math32"
+	"github.com/sago35/koebiten"
+	"github.com/sago35/koebiten/hardware"
+	"github.com/sago35/koebiten/vector"
+	"tinygo.org/x/drivers/pixel"
+)
+
+var white = pixel.NewMonochrome(0xFF, 0xFF, 0xFF)
+
+// checkPixels checks that the pixels of img are set if and only if they are
+// in want.
+func checkPixels(t *testing.T, name string, img *koebiten.Image, want func(x, y int) bool) {
+	t.Helper()
+	w, h := img.Size()
+	for y := 0; y < int(h); y++ {
+		for x := 0; x < int(w); x++ {
+			got := img.PixelAt(int16(x), int16(y)) != color.RGBA{A: 0xFF}
+			if got != want(x, y) {
+				t.Errorf("%s: pixel (%d, %d) set = %v, want %v", name, x, y, got, want(x, y))
+			}
+		}
+	}
+}
+
+func rect(p *vector.Path, x0, y0, x1, y1 float32) {
+	p.MoveTo(x0, y0)
+	p.LineTo(x1, y0)
+	p.LineTo(x1, y1)
+	p.LineTo(x0, y1)
+	p.Close()
+}
+
+func TestFill(t *testing.T) {
+	var p vector.Path
+	rect(&p, 2, 2, 10, 6)
+	img := koebiten.NewImage(16, 16)
+	p.Fill(img, white, vector.FillOptions{})
+	checkPixels(t, "rect", img, func(x, y int) bool {
+		return image.Pt(x, y).In(image.Rect(2, 2, 10, 6))
+	})
+
+	var g koebiten.GeoM
+	g.Scale(2, 2)
+	g.Translate(1, 1)
+	p = vector.Path{}
+	rect(&p, 0, 0, 4, 4)
+	img = koebiten.NewImage(16, 16)
+	p.Fill(img, white, vector.FillOptions{GeoM: g})
+	checkPixels(t, "GeoM", img, func(x, y int) bool {
+		return image.Pt(x, y).In(image.Rect(1, 1, 9, 9))
+	})
+}
+
+func TestFillRule(t *testing.T) {
+	// Two squares in the same direction.
+	var p vector.Path
+	rect(&p, 0, 0, 12, 12)
+	rect(&p, 4, 4, 8, 8)
+	hole := image.Rect(4, 4, 8, 8)
+
+	img := koebiten.NewImage(12, 12)
+	p.Fill(img, white, vector.FillOptions{FillRule: vector.FillRuleNonZero})
+	checkPixels(t, "nonzero", img, func(x, y int) bool { return true })
+
+	img = koebiten.NewImage(12, 12)
+	p.Fill(img, white, vector.FillOptions{FillRule: vector.FillRuleEvenOdd})
+	checkPixels(t, "even-odd", img, func(x, y int) bool { return !image.Pt(x, y).In(hole) })
+}
+
+func TestFillArc(t *testing.T) {
+	var p vector.Path
+	p.Arc(16, 16, 8, 0, 2*math32.Pi, vector.Clockwise)
+	p.Close()
+	img := koebiten.NewImage(32, 32)
+	p.Fill(img, white, vector.FillOptions{})
+	checkPixels(t, "circle", img, func(x, y int) bool {
+		dx, dy := float32(x)+0.5-16, float32(y)+0.5-16
+		d := math32.Hypot(dx, dy)
+		if math32.Abs(d-8) < 0.3 {
+			// Too close to the edge to tell.
+			return img.PixelAt(int16(x), int16(y)) != color.RGBA{A: 0xFF}
+		}
+		return d < 8
+	})
+}
+
+func TestStroke(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		cap  vector.LineCap
+		want image.Rectangle
+	}{
+		{"butt", vector.LineCapButt, image.Rect(2, 4, 10, 6)},
+		{"square", vector.LineCapSquare, image.Rect(1, 4, 11, 6)},
+	} {
+		var p vector.Path
+		p.MoveTo(2, 5)
+		p.LineTo(10, 5)
+		img := koebiten.NewImage(16, 16)
+		p.Stroke(img, white, vector.StrokeOptions{Width: 2, LineCap: tc.cap})
+		checkPixels(t, tc.name, img, func(x, y int) bool { return image.Pt(x, y).In(tc.want) })
+	}
+}
+
+func TestStrokeJoin(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		join   vector.LineJoin
+		corner bool
+	}{
+		{"miter", vector.LineJoinMiter, true},
+		{"bevel", vector.LineJoinBevel, false},
+		{"round", vector.LineJoinRound, false},
+	} {
+		var p vector.Path
+		p.MoveTo(4, 14)
+		p.LineTo(4, 4)
+		p.LineTo(14, 4)
+		img := koebiten.NewImage(16, 16)
+		p.Stroke(img, white, vector.StrokeOptions{Width: 4, LineJoin: tc.join})
+
+		set := func(x, y int16) bool { return img.PixelAt(x, y) != color.RGBA{A: 0xFF} }
+		if got := set(2, 2); got != tc.corner {
+			t.Errorf("%s: corner pixel set = %v, want %v", tc.name, got, tc.corner)
+		}
+		if !set(3, 3) || !set(2, 8) || !set(8, 2) {
+			t.Errorf("%s: the lines are not stroked", tc.name)
+		}
+		if set(7, 7) {
+			t.Errorf("%s: pixel inside the corner is set", tc.name)
+		}
+	}
+}
+
+// pathGame fills and strokes a path on the screen passed to Draw.
+type pathGame struct {
+	fill, stroke vector.Path
+}
+
+func (g *pathGame) Update() error {
+	return nil
+}
+
+func (g *pathGame) Draw(screen *koebiten.Image) {
+	g.fill.Fill(screen, white, vector.FillOptions{})
+	g.stroke.Stroke(screen, white, vector.StrokeOptions{Width: 2})
+}
+
+func (g *pathGame) Layout(outsideWidth, outsideHeight int) (int, int) {
+	return outsideWidth, outsideHeight
+}
+
+func TestDrawToScreen(t *testing.T) {
+	dev := hardware.NewHeadless(16, 16)
+	if err := koebiten.SetHardware(dev); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &pathGame{}
+	rect(&g.fill, 2, 2, 6, 6)
+	g.stroke.MoveTo(8, 11)
+	g.stroke.LineTo(14, 11)
+	if err := koebiten.RunGameWithOptions(g, &koebiten.RunGameOptions{MaxFrames: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	img := dev.Display().Image()
+	for _, p := range []image.Point{{2, 2}, {5, 5}, {8, 10}, {13, 11}} {
+		if got := img.RGBAAt(p.X, p.Y); got != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+			t.Errorf("pixel %v = %v, want white", p, got)
+		}
+	}
+	if got := img.RGBAAt(8, 8); got != (color.RGBA{A: 0xFF}) {
+		t.Errorf("pixel out of the paths = %v, want black", got)
+	}
+
+	// A nil Displayer draws to the screen too.
+	var p vector.Path
+	rect(&p, 0, 14, 2, 16)
+	p.Fill(nil, white, vector.FillOptions{})
+	dev.Display().Display()
+	if got := dev.Display().Image().RGBAAt(1, 15); got != (color.RGBA{R: 0xFF, G: 0xFF, B: 0xFF, A: 0xFF}) {
+		t.Errorf("pixel filled with a nil Displayer = %v, want white", got)
+	}
+}